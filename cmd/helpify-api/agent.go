@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+func agentCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "agent",
+		Usage: "manage support agent accounts",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "add",
+				Usage: "create a new support agent",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "username",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "password",
+						Required: true,
+						EnvVars: []string{
+							"HELPIFY_API_AGENT_PASSWORD",
+						},
+					},
+					&cli.StringFlag{
+						Name: "display-name",
+					},
+					&cli.BoolFlag{
+						Name:  "supervisor",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:  "team",
+						Value: "default",
+					},
+					&cli.IntFlag{
+						Name:  "max-rooms",
+						Usage: "maximum number of open rooms routed to the agent at once",
+						Value: 5,
+					},
+				},
+				Action: addAgent,
+			},
+		},
+	}
+}
+
+func addAgent(cctx *cli.Context) (err error) {
+	defer func() { _ = zap.L().Sync() }()
+
+	var sqldb *sql.DB
+	if _, sqldb, err = openDatabase(cctx); err != nil {
+		return
+	}
+
+	db := bun.NewDB(sqldb, pgdialect.New())
+	defer func() { _ = db.Close() }()
+
+	if cctx.Int("max-rooms") < 0 {
+		err = fmt.Errorf("max-rooms must not be negative")
+		return
+	}
+
+	var passwordHash []byte
+	if passwordHash, err = bcrypt.GenerateFromPassword([]byte(cctx.String("password")), bcrypt.DefaultCost); err != nil {
+		return
+	}
+
+	agent := models.Agent{
+		Username:     cctx.String("username"),
+		DisplayName:  cctx.String("display-name"),
+		PasswordHash: string(passwordHash),
+		SessionID:    strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Supervisor:   cctx.Bool("supervisor"),
+		Team:         cctx.String("team"),
+		MaxRooms:     cctx.Int("max-rooms"),
+		CreatedAt:    time.Now(),
+	}
+
+	if agent.DisplayName == "" {
+		agent.DisplayName = agent.Username
+	}
+
+	if _, err = db.NewInsert().Model(&agent).Exec(cctx.Context); err != nil {
+		err = fmt.Errorf("failed to create agent: %w", err)
+		return
+	}
+
+	zap.L().Info("created agent", zap.String("username", agent.Username), zap.Uint("id", agent.ID))
+	return
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	gorillaHandlers "github.com/gorilla/handlers"
@@ -22,9 +24,19 @@ import (
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zapio"
 
+	"github.com/helpify-project/backend/internal/connections"
 	"github.com/helpify-project/backend/internal/controllers"
+	"github.com/helpify-project/backend/internal/database"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/mailer"
+	"github.com/helpify-project/backend/internal/routing"
+	"github.com/helpify-project/backend/internal/scan"
+	"github.com/helpify-project/backend/internal/sla"
+	"github.com/helpify-project/backend/internal/storage"
 )
 
+const insecureSessionSecret = `d+rOlDT4uH5foUDCDSPFpxKnY0tcrR0U8UWfZ6ng+sYAZinksr9G/bRxLV107ze9K2zFgoJj/zz8d542fRRgFQ==`
+
 func main() {
 	ctx := context.Background()
 	ctx, _ = signal.NotifyContext(ctx, os.Interrupt)
@@ -53,9 +65,141 @@ func main() {
 					"HELPIFY_API_POSTGRES_URI",
 				},
 			},
+			&cli.BoolFlag{
+				Name:  "auto-migrate",
+				Usage: "apply pending database migrations on startup",
+				Value: false,
+				EnvVars: []string{
+					"HELPIFY_API_AUTO_MIGRATE",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "pubsub-backend",
+				Usage: "event delivery between instances, either \"postgres\" or \"memory\"",
+				Value: "postgres",
+				EnvVars: []string{
+					"HELPIFY_API_PUBSUB_BACKEND",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "routing-strategy",
+				Usage: "how new rooms are assigned to agents, one of \"round-robin\", \"least-loaded\", \"sticky\" or \"manual\"",
+				Value: "least-loaded",
+				EnvVars: []string{
+					"HELPIFY_API_ROUTING_STRATEGY",
+				},
+			},
+			&cli.DurationFlag{
+				Name:  "reopen-window",
+				Usage: "how long archived rooms may be reopened",
+				Value: 7 * 24 * time.Hour,
+				EnvVars: []string{
+					"HELPIFY_API_REOPEN_WINDOW",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "smtp-address",
+				Usage: "smtp server as host:port, email is disabled if empty",
+				EnvVars: []string{
+					"HELPIFY_API_SMTP_ADDRESS",
+				},
+			},
+			&cli.StringFlag{
+				Name: "smtp-username",
+				EnvVars: []string{
+					"HELPIFY_API_SMTP_USERNAME",
+				},
+			},
+			&cli.StringFlag{
+				Name: "smtp-password",
+				EnvVars: []string{
+					"HELPIFY_API_SMTP_PASSWORD",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "mail-from",
+				Value: "Helpify <noreply@helpify.invalid>",
+				EnvVars: []string{
+					"HELPIFY_API_MAIL_FROM",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "storage-backend",
+				Usage: "where attachments are kept, either \"local\" or \"s3\"",
+				Value: "local",
+				EnvVars: []string{
+					"HELPIFY_API_STORAGE_BACKEND",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "storage-path",
+				Usage: "directory for attachments with the local storage backend",
+				Value: "attachments",
+				EnvVars: []string{
+					"HELPIFY_API_STORAGE_PATH",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "s3-endpoint",
+				Value: "https://s3.amazonaws.com",
+				EnvVars: []string{
+					"HELPIFY_API_S3_ENDPOINT",
+				},
+			},
+			&cli.StringFlag{
+				Name: "s3-bucket",
+				EnvVars: []string{
+					"HELPIFY_API_S3_BUCKET",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "s3-region",
+				Value: "us-east-1",
+				EnvVars: []string{
+					"HELPIFY_API_S3_REGION",
+				},
+			},
+			&cli.StringFlag{
+				Name: "s3-access-key",
+				EnvVars: []string{
+					"HELPIFY_API_S3_ACCESS_KEY",
+				},
+			},
+			&cli.StringFlag{
+				Name: "s3-secret-key",
+				EnvVars: []string{
+					"HELPIFY_API_S3_SECRET_KEY",
+				},
+			},
+			&cli.Int64Flag{
+				Name:  "attachment-max-size",
+				Usage: "largest accepted upload in bytes",
+				Value: controllers.DefaultMaxAttachmentSize,
+				EnvVars: []string{
+					"HELPIFY_API_ATTACHMENT_MAX_SIZE",
+				},
+			},
+			&cli.StringFlag{
+				Name:  "clamd-address",
+				Usage: "clamd to scan uploads with, as host:port or unix:///path/to/clamd.sock; malware scanning is disabled if empty",
+				EnvVars: []string{
+					"HELPIFY_API_CLAMD_ADDRESS",
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "strip-image-metadata",
+				Usage: "remove exif metadata such as locations from uploaded images",
+				Value: true,
+				EnvVars: []string{
+					"HELPIFY_API_STRIP_IMAGE_METADATA",
+				},
+			},
 			&cli.StringFlag{
 				Name:  "session-secret",
-				Value: `d+rOlDT4uH5foUDCDSPFpxKnY0tcrR0U8UWfZ6ng+sYAZinksr9G/bRxLV107ze9K2zFgoJj/zz8d542fRRgFQ==`,
+				Usage: "base64 encoded ed25519 key signing sessions and support tokens, a random key is used if empty",
+				EnvVars: []string{
+					"HELPIFY_API_SESSION_SECRET",
+				},
 			},
 		},
 		Before: func(cctx *cli.Context) (err error) {
@@ -63,6 +207,10 @@ func main() {
 			return
 		},
 		Action: entrypoint,
+		Commands: []*cli.Command{
+			migrateCommand(),
+			agentCommand(),
+		},
 	}
 
 	if err := app.RunContext(ctx, os.Args); err != nil {
@@ -97,17 +245,26 @@ func setupLogging(debugMode bool) error {
 	return nil
 }
 
+func openDatabase(cctx *cli.Context) (dbConfig *pgx.ConnConfig, sqldb *sql.DB, err error) {
+	if dbConfig, err = pgx.ParseConfig(cctx.String("postgres-uri")); err != nil {
+		err = fmt.Errorf("unable to parse postgres uri: %w", err)
+		return
+	}
+
+	sqldb = stdlib.OpenDB(*dbConfig)
+	return
+}
+
 func entrypoint(cctx *cli.Context) (err error) {
 	ctx := cctx.Context
 	defer func() { _ = zap.L().Sync() }()
 
 	var dbConfig *pgx.ConnConfig
-	if dbConfig, err = pgx.ParseConfig(cctx.String("postgres-uri")); err != nil {
-		err = fmt.Errorf("unable to parse postgres uri: %w", err)
+	var sqldb *sql.DB
+	if dbConfig, sqldb, err = openDatabase(cctx); err != nil {
 		return
 	}
 
-	sqldb := stdlib.OpenDB(*dbConfig)
 	db := bun.NewDB(sqldb, pgdialect.New())
 	defer func() { _ = db.Close() }()
 
@@ -126,6 +283,100 @@ func entrypoint(cctx *cli.Context) (err error) {
 		return
 	}
 
+	if cctx.Bool("auto-migrate") {
+		if err = database.Migrate(sqldb, "up"); err != nil {
+			err = fmt.Errorf("failed to apply migrations: %w", err)
+			return
+		}
+	}
+
+	if err = database.CheckSchema(sqldb); err != nil {
+		err = fmt.Errorf("refusing to start, run migrations first: %w", err)
+		return
+	}
+
+	// Formerly the default value, anyone can sign support tokens with it
+	if cctx.String("session-secret") == insecureSessionSecret {
+		err = fmt.Errorf("refusing to start with the published session secret, generate a new one")
+		return
+	}
+
+	var eventBackend events.Backend
+	switch backend := cctx.String("pubsub-backend"); backend {
+	case "postgres":
+		eventBackend = events.NewPostgresBackend(sqldb, dbConfig)
+	case "memory":
+		eventBackend = events.NewMemoryBackend()
+	default:
+		err = fmt.Errorf("unsupported pubsub backend: %s", backend)
+		return
+	}
+
+	var routingStrategy routing.Strategy
+	if routingStrategy, err = routing.ParseStrategy(cctx.String("routing-strategy")); err != nil {
+		return
+	}
+
+	hub := events.NewHub(eventBackend)
+	go func() {
+		if err := hub.Run(ctx); err != nil {
+			zap.L().Error("event hub stopped", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		if err := sla.NewScheduler(db, hub).Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			zap.L().Error("sla scheduler stopped", zap.Error(err))
+		}
+	}()
+
+	agentConnections := connections.NewTracker(db, hub)
+	go func() {
+		if err := agentConnections.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			zap.L().Error("agent connection tracker stopped", zap.Error(err))
+		}
+	}()
+
+	var mailQueue *mailer.Queue
+	if smtpAddress := cctx.String("smtp-address"); smtpAddress != "" {
+		var smtpMailer *mailer.SMTPMailer
+		if smtpMailer, err = mailer.NewSMTPMailer(smtpAddress, cctx.String("mail-from"), cctx.String("smtp-username"), cctx.String("smtp-password")); err != nil {
+			return
+		}
+
+		mailQueue = mailer.NewQueue(smtpMailer, 256)
+		go func() {
+			if err := mailQueue.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				zap.L().Error("mail queue stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	var attachmentStore storage.Store
+	switch backend := cctx.String("storage-backend"); backend {
+	case "local":
+		if attachmentStore, err = storage.NewLocalStore(cctx.String("storage-path")); err != nil {
+			return
+		}
+	case "s3":
+		if attachmentStore, err = storage.NewS3Store(cctx.String("s3-endpoint"), cctx.String("s3-bucket"), cctx.String("s3-region"), cctx.String("s3-access-key"), cctx.String("s3-secret-key")); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("unsupported storage backend: %s", backend)
+		return
+	}
+
+	scanners := []scan.Scanner{scan.MIMESniffer{}}
+	if clamdAddress := cctx.String("clamd-address"); strings.HasPrefix(clamdAddress, "unix://") {
+		scanners = append(scanners, scan.NewClamdScanner("unix", strings.TrimPrefix(clamdAddress, "unix://")))
+	} else if clamdAddress != "" {
+		scanners = append(scanners, scan.NewClamdScanner("tcp", strings.TrimPrefix(clamdAddress, "tcp://")))
+	}
+	if cctx.Bool("strip-image-metadata") {
+		scanners = append(scanners, scan.EXIFStripper{})
+	}
+
 	// XXX: Render pls
 	listenAddr := cctx.String("http-listen-address")
 	if port := os.Getenv("PORT"); port != "" {
@@ -172,8 +423,17 @@ func entrypoint(cctx *cli.Context) (err error) {
 		(&controllers.GoDebugController{}).Register(router)
 	}
 	(&controllers.ChatController{
-		DB:            db,
-		SessionSecret: cctx.String("session-secret"),
+		DB:                db,
+		Events:            hub,
+		Connections:       agentConnections,
+		Router:            routing.NewRouter(routingStrategy),
+		SLA:               sla.NewTimers(sla.DefaultTargets),
+		ReopenWindow:      cctx.Duration("reopen-window"),
+		Mail:              mailQueue,
+		Storage:           attachmentStore,
+		Scanner:           scan.NewPipeline(scanners...),
+		MaxAttachmentSize: cctx.Int64("attachment-max-size"),
+		SessionSecret:     cctx.String("session-secret"),
 	}).Register(router)
 	(&controllers.HealthController{}).Register(router)
 
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database"
+)
+
+func migrateCommand() *cli.Command {
+	subcommand := func(name string, usage string) *cli.Command {
+		return &cli.Command{
+			Name:  name,
+			Usage: usage,
+			Action: func(cctx *cli.Context) error {
+				return migrate(cctx, name)
+			},
+		}
+	}
+
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "manage the database schema",
+		Subcommands: []*cli.Command{
+			subcommand("up", "apply all pending migrations"),
+			subcommand("down", "roll back the latest migration"),
+			subcommand("status", "show applied and pending migrations"),
+			subcommand("redo", "roll back and re-apply the latest migration"),
+		},
+	}
+}
+
+func migrate(cctx *cli.Context, command string) (err error) {
+	defer func() { _ = zap.L().Sync() }()
+
+	var sqldb *sql.DB
+	if _, sqldb, err = openDatabase(cctx); err != nil {
+		return
+	}
+	defer func() { _ = sqldb.Close() }()
+
+	if err = database.Migrate(sqldb, command); err != nil {
+		err = fmt.Errorf("migrate %s failed: %w", command, err)
+	}
+	return
+}
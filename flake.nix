@@ -24,7 +24,7 @@
       rec {
         devShell = pkgs.mkShell {
           buildInputs = [
-            pkgs.go_1_19
+            pkgs.go_1_20
             pkgs.gopls
             pkgs.postgresql
           ];
@@ -0,0 +1,99 @@
+// Package agents holds agent status handling shared by the RPC services and
+// the connection tracker
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+// Status of an agent as published on events.AgentsTopic
+type Status struct {
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	DisplayName     string     `json:"displayName"`
+	Team            string     `json:"team"`
+	Status          string     `json:"status"`
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+	MaxRooms        int        `json:"maxRooms"`
+	OpenRooms       int        `json:"openRooms"`
+}
+
+func (a *Status) FromModel(agent models.Agent) {
+	a.ID = fmt.Sprint(agent.ID)
+	a.Username = agent.Username
+	a.DisplayName = agent.DisplayName
+	a.Team = agent.Team
+	a.Status = agent.Status
+	a.StatusChangedAt = agent.StatusChangedAt
+	a.MaxRooms = agent.MaxRooms
+}
+
+// Entry is an agent along with the number of open rooms assigned to them
+type Entry struct {
+	models.Agent `bun:",extend"`
+
+	OpenRooms int `bun:",scanonly"`
+}
+
+func (e Entry) ToStatus() (status Status) {
+	status.FromModel(e.Agent)
+	status.OpenRooms = e.OpenRooms
+	return
+}
+
+// Select selects agents along with the number of open rooms assigned to them
+func Select(db bun.IDB, dest interface{}) *bun.SelectQuery {
+	return db.NewSelect().
+		Model(dest).
+		ModelTableExpr("agents AS a").
+		ColumnExpr("a.*").
+		ColumnExpr(`(
+			SELECT COUNT(*) FROM joined_rooms AS jr
+			JOIN rooms AS r ON r.id = jr.room_id
+			WHERE jr.user_id = a.session_id
+			AND jr.assigned_at IS NOT NULL
+			AND r.archived_at IS NULL
+		) AS open_rooms`)
+}
+
+// UpdateStatus changes the status of the agent with given session id, reporting
+// whether it changed. Callers publish the change with Publish once it is committed.
+func UpdateStatus(ctx context.Context, db bun.IDB, sessionID string, status string) (changed bool, err error) {
+	var res sql.Result
+	res, err = db.NewUpdate().
+		Model((*models.Agent)(nil)).
+		Set("status = ?", status).
+		Set("status_changed_at = ?", time.Now()).
+		Where("session_id = ?", sessionID).
+		Where("status != ?", status).
+		Exec(ctx)
+	if err != nil {
+		return
+	}
+
+	affected, _ := res.RowsAffected()
+	changed = affected > 0
+	return
+}
+
+// Publish notifies supervisors of the current status of the agent
+func Publish(ctx context.Context, db bun.IDB, hub *events.Hub, sessionID string) {
+	var entry Entry
+	if err := Select(db, &entry).Where("a.session_id = ?", sessionID).Scan(ctx); err != nil {
+		zap.L().Error("failed to load agent status", zap.Error(err))
+		return
+	}
+
+	if err := hub.Publish(ctx, events.AgentsTopic, events.TypeAgentStatus, entry.ToStatus()); err != nil {
+		zap.L().Error("failed to publish agent status", zap.Error(err))
+	}
+}
@@ -5,4 +5,5 @@ type ContextKey string
 var (
 	SessionID        ContextKey = "ha:sid"
 	SupportPersonnel ContextKey = "ha:sp"
+	Supervisor       ContextKey = "ha:sv"
 )
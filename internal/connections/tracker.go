@@ -0,0 +1,180 @@
+// Package connections keeps track of agent websocket connections across all
+// running instances, so agents are only marked offline once their last one is gone.
+package connections
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/agents"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+// Tracker records connections of this instance in the agent_connections table.
+// Rows of instances which stopped refreshing their heartbeat expire.
+type Tracker struct {
+	DB         *bun.DB
+	Events     *events.Hub
+	InstanceID string
+	Interval   time.Duration
+	Expiry     time.Duration
+}
+
+func NewTracker(db *bun.DB, hub *events.Hub) *Tracker {
+	return &Tracker{
+		DB:         db,
+		Events:     hub,
+		InstanceID: strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Interval:   30 * time.Second,
+		Expiry:     2 * time.Minute,
+	}
+}
+
+// Connected records a new connection of the agent, returning its id for Disconnected
+func (t *Tracker) Connected(ctx context.Context, sessionID string) (id uint, err error) {
+	conn := models.AgentConnection{
+		SessionID:  sessionID,
+		InstanceID: t.InstanceID,
+	}
+
+	err = t.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) (err error) {
+		if err = lockAgent(ctx, tx, sessionID); err != nil {
+			return
+		}
+
+		_, err = tx.NewInsert().
+			Model(&conn).
+			Exec(ctx)
+		return
+	})
+	id = conn.ID
+	return
+}
+
+// Disconnected removes the connection, marking the agent offline if it was their last one
+func (t *Tracker) Disconnected(ctx context.Context, id uint, sessionID string) (err error) {
+	var offline bool
+	err = t.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) (err error) {
+		if err = lockAgent(ctx, tx, sessionID); err != nil {
+			return
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.AgentConnection)(nil)).
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return
+		}
+
+		offline, err = t.markOfflineIfGone(ctx, tx, sessionID)
+		return
+	})
+	if err == nil && offline {
+		agents.Publish(ctx, t.DB, t.Events, sessionID)
+	}
+	return
+}
+
+// Run refreshes the heartbeat of this instance and expires connections of
+// instances which are gone, until ctx is done
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		_, err := t.DB.NewUpdate().
+			Model((*models.AgentConnection)(nil)).
+			Set("heartbeat_at = now()").
+			Where("instance_id = ?", t.InstanceID).
+			Exec(ctx)
+		if err != nil {
+			zap.L().Error("failed to refresh agent connections", zap.Error(err))
+		}
+
+		if err := t.expire(ctx); err != nil {
+			zap.L().Error("failed to expire agent connections", zap.Error(err))
+		}
+	}
+}
+
+func (t *Tracker) expire(ctx context.Context) (err error) {
+	var sessionIDs []string
+	err = t.DB.NewSelect().
+		Model((*models.AgentConnection)(nil)).
+		Distinct().
+		Column("session_id").
+		Where("heartbeat_at < now() - make_interval(secs => ?)", t.Expiry.Seconds()).
+		Scan(ctx, &sessionIDs)
+	if err != nil {
+		return
+	}
+
+	for _, sessionID := range sessionIDs {
+		var offline bool
+		err = t.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) (err error) {
+			if err = lockAgent(ctx, tx, sessionID); err != nil {
+				return
+			}
+
+			_, err = tx.NewDelete().
+				Model((*models.AgentConnection)(nil)).
+				Where("session_id = ?", sessionID).
+				Where("heartbeat_at < now() - make_interval(secs => ?)", t.Expiry.Seconds()).
+				Exec(ctx)
+			if err != nil {
+				return
+			}
+
+			offline, err = t.markOfflineIfGone(ctx, tx, sessionID)
+			return
+		})
+		if err != nil {
+			return
+		}
+
+		if offline {
+			agents.Publish(ctx, t.DB, t.Events, sessionID)
+		}
+	}
+	return
+}
+
+// markOfflineIfGone marks the agent offline once no connection is left,
+// reporting whether the status changed
+func (t *Tracker) markOfflineIfGone(ctx context.Context, tx bun.Tx, sessionID string) (offline bool, err error) {
+	var connected bool
+	connected, err = tx.NewSelect().
+		Model((*models.AgentConnection)(nil)).
+		Where("session_id = ?", sessionID).
+		Where("heartbeat_at >= now() - make_interval(secs => ?)", t.Expiry.Seconds()).
+		Exists(ctx)
+	if err != nil || connected {
+		return
+	}
+
+	return agents.UpdateStatus(ctx, tx, sessionID, models.AgentOffline)
+}
+
+// lockAgent serializes connection changes of the agent across instances
+func lockAgent(ctx context.Context, tx bun.Tx, sessionID string) error {
+	_, err := tx.NewSelect().
+		Model((*models.Agent)(nil)).
+		Column("id").
+		Where("session_id = ?", sessionID).
+		For("UPDATE").
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+const (
+	supportTokenAudience = "support"
+	supportTokenLifetime = 12 * time.Hour
+
+	maxLoginRequestSize = 4096
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid credentials")
+
+	// Compared against when the agent does not exist, so response timing does
+	// not reveal valid usernames
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Supervisor  bool   `json:"supervisor"`
+}
+
+func (c *ChatController) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLoginRequestSize)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	agent, err := c.authenticateAgent(r.Context(), req.Username, req.Password)
+	if errors.Is(err, errInvalidCredentials) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		zap.L().Error("failed to authenticate agent", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(supportTokenLifetime)
+	token := newToken()
+	token.SetIssuer("helpify")
+	token.SetExpiration(expiresAt)
+	token.SetIssuedAt(now)
+	token.SetNotBefore(now)
+	token.SetSubject(agent.SessionID)
+	token.SetAudience(supportTokenAudience)
+
+	http.SetCookie(w, supportCookie(token.V4Sign(c.sessionKey, nil), expiresAt))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(loginResponse{
+		Username:    agent.Username,
+		DisplayName: agent.DisplayName,
+		Supervisor:  agent.Supervisor,
+	})
+}
+
+func (c *ChatController) handleLogout(w http.ResponseWriter, r *http.Request) {
+	cookie := supportCookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+
+	http.SetCookie(w, cookie)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ChatController) authenticateAgent(ctx context.Context, username string, password string) (agent models.Agent, err error) {
+	err = c.DB.NewSelect().
+		Model(&agent).
+		Where("username = ?", username).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("helpify"), bcrypt.DefaultCost)
+		})
+
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		err = errInvalidCredentials
+		return
+	} else if err != nil {
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(agent.PasswordHash), []byte(password)) != nil {
+		err = errInvalidCredentials
+	}
+	return
+}
+
+// verifiedSupportAgent returns the agent whose valid support token the request
+// carries. Rights are taken from the agent row rather than the token, so removed
+// or demoted agents lose them right away.
+func (c *ChatController) verifiedSupportAgent(r *http.Request) (agent models.Agent, ok bool) {
+	cookie, err := r.Cookie(chatSupportCookieName)
+	if err != nil {
+		return
+	}
+
+	token, err := c.supportTokenParser.ParseV4Public(c.sessionKey.Public(), cookie.Value, nil)
+	if err != nil {
+		zap.L().Debug("invalid support token", zap.Error(err))
+		return
+	}
+
+	agentSID, err := token.GetSubject()
+	if err != nil || agentSID == "" {
+		return
+	}
+
+	err = c.DB.NewSelect().
+		Model(&agent).
+		Where("session_id = ?", agentSID).
+		Scan(r.Context())
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	} else if err != nil {
+		zap.L().Error("failed to find agent of support token", zap.Error(err))
+		return
+	}
+
+	ok = true
+	return
+}
+
+func supportCookie(value string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     chatSupportCookieName,
+		Value:    value,
+		Path:     "/chat",
+		Expires:  expiresAt,
+		SameSite: http.SameSiteNoneMode,
+		HttpOnly: true,
+		Secure:   true,
+		// TODO: need allowed domains from the configuration
+		//Domain: r.URL.Hostname(),
+		//Secure: r.URL.Scheme == "https",
+	}
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// agentDisconnected removes the connection, marking the agent offline once none
+// of their websocket connections to any instance remain
+func (c *ChatController) agentDisconnected(connID uint, sid string) {
+	// The request context is gone by now
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.Connections.Disconnected(ctx, connID, sid); err != nil {
+		zap.L().Error("failed to remove agent connection", zap.Error(err))
+	}
+}
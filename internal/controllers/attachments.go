@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/jsonrpc"
+	"github.com/helpify-project/backend/internal/scan"
+	"github.com/helpify-project/backend/internal/storage"
+)
+
+const (
+	DefaultMaxAttachmentSize = 10 << 20
+
+	// Covers receiving the file and running the scanners on it
+	uploadTimeout = 2 * time.Minute
+
+	attachmentFormField = "file"
+	maxFilenameLength   = 255
+)
+
+// Content types accepted for uploads. Images are displayed inline, everything
+// else is offered as a download.
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// handleUpload scans and stores a single file for the room. The returned attachment
+// id can then be sent along with a message. Files rejected by the scanners are
+// quarantined, they can still be sent but show up without content.
+func (c *ChatController) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r = c.prepareRequest(r, c.verifiedSessionID(r))
+	ctx := r.Context()
+
+	sid := ctx.Value(cctx.SessionID).(string)
+	if sid == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	roomID, ok := c.memberRoomID(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	maxSize := c.MaxAttachmentSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxAttachmentSize
+	}
+
+	// Large files on slow links and scanning take longer than the server wide timeouts
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(uploadTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		zap.L().Debug("failed to extend upload read deadline", zap.Error(err))
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		zap.L().Debug("failed to extend upload write deadline", zap.Error(err))
+	}
+
+	// Leave some room for the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+64<<10)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	part, err := reader.NextPart()
+	for err == nil && part.FormName() != attachmentFormField {
+		part, err = reader.NextPart()
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = part.Close() }()
+
+	contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if err != nil || !allowedAttachmentTypes[contentType] {
+		writeUploadError(w, http.StatusUnsupportedMediaType, "file type is not allowed")
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	} else if int64(len(content)) > maxSize {
+		writeUploadError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file is larger than %d bytes", maxSize))
+		return
+	}
+
+	file := scan.File{
+		Filename:    attachmentFilename(part.FileName()),
+		ContentType: contentType,
+		Content:     content,
+	}
+
+	attachment := models.Attachment{
+		RoomID:      roomID,
+		Uploader:    sid,
+		StorageKey:  fmt.Sprintf("rooms/%d/%s", roomID, strings.ReplaceAll(uuid.New().String(), "-", "")),
+		Filename:    file.Filename,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+		ScanStatus:  models.AttachmentClean,
+	}
+
+	// Rejected files are kept aside for review, the uploader learns why
+	var rejection *scan.Rejection
+	if err = c.Scanner.Run(ctx, &file); errors.As(err, &rejection) {
+		zap.L().Info("quarantined attachment", zap.Uint("room", roomID), zap.String("reason", rejection.Error()))
+		attachment.StorageKey = "quarantine/" + attachment.StorageKey
+		attachment.ScanStatus = models.AttachmentQuarantined
+		attachment.QuarantineReason = &rejection.Reason
+	} else if err != nil {
+		zap.L().Error("failed to scan attachment", zap.Error(err))
+		writeUploadError(w, http.StatusServiceUnavailable, "file could not be scanned, try again later")
+		return
+	} else {
+		// Scanners may have rewritten the file
+		content = file.Content
+	}
+	attachment.Size = int64(len(content))
+
+	if err = c.Storage.Put(ctx, attachment.StorageKey, bytes.NewReader(content), attachment.Size, attachment.ContentType); err != nil {
+		zap.L().Error("failed to store attachment", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = c.DB.NewInsert().Model(&attachment).Exec(ctx); err != nil {
+		zap.L().Error("failed to insert attachment", zap.Error(err))
+		if err := c.Storage.Delete(ctx, attachment.StorageKey); err != nil {
+			zap.L().Error("failed to delete orphaned attachment", zap.Error(err))
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	if attachment.ScanStatus == models.AttachmentQuarantined {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(jsonrpc.AttachmentFromModel(attachment))
+}
+
+// handleDownload serves an attachment to members of the room it was uploaded to
+func (c *ChatController) handleDownload(w http.ResponseWriter, r *http.Request) {
+	r = c.prepareRequest(r, c.verifiedSessionID(r))
+	ctx := r.Context()
+
+	sid := ctx.Value(cctx.SessionID).(string)
+	if sid == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	attachmentID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var attachment models.Attachment
+	err = c.DB.NewSelect().
+		Model(&attachment).
+		Where("id = ?", attachmentID).
+		Where("scan_status = ?", models.AttachmentClean).
+		// Files go away together with their deleted message
+		Where("NOT EXISTS (SELECT 1 FROM messages AS m WHERE m.id = ?TableAlias.message_id AND m.deleted_at IS NOT NULL)").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		zap.L().Error("failed to find attachment", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Unsent uploads are only visible to the uploader
+	if attachment.MessageID == nil && attachment.Uploader != sid {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if _, ok := c.memberRoomID(w, r, fmt.Sprint(attachment.RoomID)); !ok {
+		return
+	}
+
+	body, err := c.Storage.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		zap.L().Error("failed to open attachment", zap.Uint("attachment", attachment.ID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+
+	if _, err = io.Copy(newDeadlineWriter(w, streamWriteTimeout), body); err != nil {
+		zap.L().Debug("failed to send attachment", zap.Error(err))
+	}
+}
+
+// memberRoomID parses the room id and checks that the session has joined it,
+// responding with not found otherwise
+func (c *ChatController) memberRoomID(w http.ResponseWriter, r *http.Request, rawRoomID string) (roomID uint, ok bool) {
+	ctx := r.Context()
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	parsed, err := strconv.ParseUint(rawRoomID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	roomID = uint(parsed)
+
+	ok, err = c.DB.NewSelect().
+		Model((*models.JoinedRoom)(nil)).
+		Where("room_id = ?", roomID).
+		Where("user_id = ?", sid).
+		Exists(ctx)
+	if err != nil {
+		zap.L().Error("failed to check room membership", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return roomID, false
+	} else if !ok {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	return
+}
+
+type uploadError struct {
+	Error string `json:"error"`
+}
+
+func writeUploadError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(uploadError{Error: reason})
+}
+
+// attachmentFilename strips any path from the client supplied name
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "file"
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[len(runes)-maxFilenameLength:])
+	}
+	return name
+}
@@ -3,7 +3,6 @@ package controllers
 import (
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"sync"
@@ -17,9 +16,16 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/connections"
+	"github.com/helpify-project/backend/internal/events"
 	"github.com/helpify-project/backend/internal/jsonrpc"
+	"github.com/helpify-project/backend/internal/mailer"
 	"github.com/helpify-project/backend/internal/router"
+	"github.com/helpify-project/backend/internal/routing"
 	"github.com/helpify-project/backend/internal/rpc"
+	"github.com/helpify-project/backend/internal/scan"
+	"github.com/helpify-project/backend/internal/sla"
+	"github.com/helpify-project/backend/internal/storage"
 )
 
 var _ router.Controller = (*ChatController)(nil)
@@ -34,13 +40,23 @@ var (
 )
 
 type ChatController struct {
-	DB            *bun.DB
-	SessionSecret string
-
-	sessionKey  paseto.V4AsymmetricSecretKey
-	tokenParser paseto.Parser
-	upgrader    *websocket.Upgrader
-	rpc         *rpc.Server
+	DB                *bun.DB
+	Events            *events.Hub
+	Connections       *connections.Tracker
+	Router            *routing.Router
+	SLA               *sla.Timers
+	ReopenWindow      time.Duration
+	Mail              *mailer.Queue
+	Storage           storage.Store
+	Scanner           *scan.Pipeline
+	MaxAttachmentSize int64
+	SessionSecret     string
+
+	sessionKey         paseto.V4AsymmetricSecretKey
+	tokenParser        paseto.Parser
+	supportTokenParser paseto.Parser
+	upgrader           *websocket.Upgrader
+	rpc                *rpc.Server
 }
 
 func (c *ChatController) handleChat(w http.ResponseWriter, r *http.Request) {
@@ -58,17 +74,37 @@ func (c *ChatController) handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	r = c.prepareRequest(r, sid)
+	if r.Context().Value(cctx.SupportPersonnel).(bool) {
+		supportSID := r.Context().Value(cctx.SessionID).(string)
+		if connID, err := c.Connections.Connected(r.Context(), supportSID); err != nil {
+			zap.L().Error("failed to record agent connection", zap.Error(err))
+		} else {
+			defer c.agentDisconnected(connID, supportSID)
+		}
+	}
+
 	c.rpc.HandleWebsocketConnection(r, conn)
 }
 
 func (c *ChatController) Register(router *mux.Router) {
 	var err error
-	if c.sessionKey, err = loadPasetoPrivateKey(c.SessionSecret); err != nil {
-		zap.L().Error("failed to decode session private key, using random key", zap.Error(err))
+	if c.SessionSecret == "" {
+		// Sessions and support tokens do not survive restarts, nor work across instances
+		zap.L().Warn("no session secret configured, using a random key")
+		c.sessionKey = paseto.NewV4AsymmetricSecretKey()
+	} else if c.sessionKey, err = loadPasetoPrivateKey(c.SessionSecret); err != nil {
+		zap.L().Fatal("failed to decode session private key", zap.Error(err))
 	}
 
 	c.tokenParser = paseto.MakeParser([]paseto.Rule{
 		paseto.IssuedBy("helpify"),
+		paseto.ForAudience("user"),
+		paseto.NotExpired(),
+	})
+
+	c.supportTokenParser = paseto.MakeParser([]paseto.Rule{
+		paseto.IssuedBy("helpify"),
+		paseto.ForAudience(supportTokenAudience),
 		paseto.NotExpired(),
 	})
 
@@ -88,8 +124,32 @@ func (c *ChatController) Register(router *mux.Router) {
 	//log.Root().SetHandler(log.StderrHandler)
 
 	c.rpc = rpc.NewServer()
-	c.rpc.RegisterName("chat", jsonrpc.NewChatService(c.DB))
-	c.rpc.RegisterName("room", jsonrpc.NewRoomService(c.DB))
+	c.rpc.RequirePolicies()
+
+	chatService := jsonrpc.NewChatService(c.DB, c.Events, c.SLA)
+	if err = c.rpc.RegisterNameWithPolicies("chat", chatService, jsonrpc.ChatPolicies(chatService)); err != nil {
+		zap.L().Fatal("failed to register chat service", zap.Error(err))
+	}
+
+	roomService := jsonrpc.NewRoomService(c.DB, c.Events, c.Router, c.SLA, c.ReopenWindow, c.Mail)
+	if err = c.rpc.RegisterNameWithPolicies("room", roomService, jsonrpc.RoomPolicies(roomService)); err != nil {
+		zap.L().Fatal("failed to register room service", zap.Error(err))
+	}
+
+	agentService := jsonrpc.NewAgentService(c.DB, c.Events)
+	if err = c.rpc.RegisterNameWithPolicies("agent", agentService, jsonrpc.AgentPolicies(agentService)); err != nil {
+		zap.L().Fatal("failed to register agent service", zap.Error(err))
+	}
+
+	macroService := jsonrpc.NewMacroService(c.DB, c.Events)
+	if err = c.rpc.RegisterNameWithPolicies("macro", macroService, jsonrpc.MacroPolicies(macroService)); err != nil {
+		zap.L().Fatal("failed to register macro service", zap.Error(err))
+	}
+
+	searchService := jsonrpc.NewSearchService(c.DB, c.Events)
+	if err = c.rpc.RegisterNameWithPolicies("search", searchService, jsonrpc.SearchPolicies(searchService)); err != nil {
+		zap.L().Fatal("failed to register search service", zap.Error(err))
+	}
 
 	router.HandleFunc("/chat/ws", c.handleChat).Methods(http.MethodGet)
 
@@ -111,24 +171,11 @@ func (c *ChatController) Register(router *mux.Router) {
 		c.rpc.ServeHTTP(w, c.prepareRequest(r, sid))
 	})
 
-	// XXX: easter egg, or lazy dev job?
-	router.HandleFunc("/chat/iamsupport", func(w http.ResponseWriter, r *http.Request) {
-		http.SetCookie(w, &http.Cookie{
-			Name:     chatSupportCookieName,
-			Value:    "true",
-			Path:     "/chat",
-			Expires:  time.Now().Add(24 * time.Hour),
-			SameSite: http.SameSiteNoneMode,
-			HttpOnly: true,
-			Secure:   true,
-			// TODO: need allowed domains from the configuration
-			//Domain: r.URL.Hostname(),
-			//Secure: r.URL.Scheme == "https",
-		})
-
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, "OK")
-	}).Methods(http.MethodGet)
+	router.HandleFunc("/chat/rooms/{id:[0-9]+}/transcript", c.handleTranscript).Methods(http.MethodGet)
+	router.HandleFunc("/chat/rooms/{id:[0-9]+}/attachments", c.handleUpload).Methods(http.MethodPost)
+	router.HandleFunc("/chat/attachments/{id:[0-9]+}", c.handleDownload).Methods(http.MethodGet)
+	router.HandleFunc("/chat/login", c.handleLogin).Methods(http.MethodPost)
+	router.HandleFunc("/chat/logout", c.handleLogout).Methods(http.MethodPost)
 }
 
 func (c *ChatController) getOrCreateChatSessionCookie(r *http.Request) (sid string, newHeader http.Header, err error) {
@@ -197,18 +244,42 @@ func (c *ChatController) getOrCreateChatSessionCookie(r *http.Request) (sid stri
 	return
 }
 
+// verifiedSessionID returns the session id from a valid session cookie, without
+// starting a new session like getOrCreateChatSessionCookie does
+func (c *ChatController) verifiedSessionID(r *http.Request) (sid string) {
+	cookie, err := r.Cookie(chatSessionCookieName)
+	if err != nil {
+		return
+	}
+
+	token, err := c.tokenParser.ParseV4Public(c.sessionKey.Public(), cookie.Value, nil)
+	if err != nil {
+		zap.L().Debug("invalid token", zap.Error(err))
+		return
+	}
+
+	if sid, err = token.GetSubject(); err != nil {
+		zap.L().Debug("failed to get sid from token", zap.Error(err))
+	}
+	return
+}
+
 func (c *ChatController) prepareRequest(r *http.Request, sid string) *http.Request {
 	supportPersonnel := false
+	supervisor := false
 
-	var supportCookie *http.Cookie
-	if supportCookie, _ = r.Cookie(chatSupportCookieName); supportCookie != nil {
+	// Agents act under their own stable session id
+	if agent, ok := c.verifiedSupportAgent(r); ok {
+		sid = agent.SessionID
 		supportPersonnel = true
+		supervisor = agent.Supervisor
 	}
 
 	return r.WithContext(cctx.WithValues(
 		r.Context(),
 		cctx.SessionID, sid,
 		cctx.SupportPersonnel, supportPersonnel,
+		cctx.Supervisor, supervisor,
 	))
 }
 
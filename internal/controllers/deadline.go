@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Time allowed for each write of a streamed response
+const streamWriteTimeout = 15 * time.Second
+
+// deadlineWriter pushes the write deadline of the response back before every
+// write. Long responses such as transcripts or large attachments then outlive
+// the server wide write timeout, but still fail if the client stops reading.
+type deadlineWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func newDeadlineWriter(w http.ResponseWriter, timeout time.Duration) *deadlineWriter {
+	return &deadlineWriter{
+		w:       w,
+		rc:      http.NewResponseController(w),
+		timeout: timeout,
+	}
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.rc.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
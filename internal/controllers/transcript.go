@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/transcript"
+)
+
+// handleTranscript streams all messages of a room in the requested format. Customers
+// may only export their own rooms, internal notes are left out for them.
+func (c *ChatController) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	r = c.prepareRequest(r, c.verifiedSessionID(r))
+	ctx := r.Context()
+
+	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+	if sid == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = transcript.FormatJSON
+	}
+
+	roomID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var room models.Room
+	err = c.DB.NewSelect().
+		Model(&room).
+		Where("id = ?", roomID).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !supportPersonnel && room.Owner != sid) {
+		// Rooms of others are indistinguishable from missing ones
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		zap.L().Error("failed to find room", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer, err := newTranscriptWriter(w, format, room.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Headers are gone once streaming has started, all we can do is cut the response short
+	if err = transcript.Stream(ctx, c.DB, room.ID, supportPersonnel, writer); err != nil {
+		zap.L().Error("failed to stream transcript", zap.Uint("room", room.ID), zap.Error(err))
+	}
+}
+
+// newTranscriptWriter sets the response headers before creating the writer, as
+// some formats write their preamble right away
+func newTranscriptWriter(w http.ResponseWriter, format string, roomID uint) (writer transcript.Writer, err error) {
+	w.Header().Set("Content-Type", transcript.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d.%s"`, roomID, format))
+
+	writer, err = transcript.NewWriter(format, newDeadlineWriter(w, streamWriteTimeout), fmt.Sprintf("Helpify conversation #%d", roomID))
+	if err != nil {
+		// Nothing was written for unsupported formats
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Disposition")
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/helpify-project/backend/internal/transcript"
+)
+
+func TestTranscriptHeadersPrecedeBody(t *testing.T) {
+	formats := []string{transcript.FormatJSON, transcript.FormatText, transcript.FormatHTML, transcript.FormatCSV}
+	for _, format := range formats {
+		rec := httptest.NewRecorder()
+
+		writer, err := newTranscriptWriter(rec, format, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// The recorder keeps the headers as they were when the body started
+		res := rec.Result()
+		if got := res.Header.Get("Content-Type"); got != transcript.ContentType(format) {
+			t.Errorf("%s: unexpected content type %q", format, got)
+		}
+		if got, want := res.Header.Get("Content-Disposition"), `attachment; filename="room-7.`+format+`"`; got != want {
+			t.Errorf("%s: unexpected content disposition %q", format, got)
+		}
+	}
+}
+
+func TestTranscriptRejectsUnknownFormat(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if _, err := newTranscriptWriter(rec, "pdf", 7); err == nil {
+		t.Fatal("expected unsupported format to fail")
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != "" {
+		t.Fatalf("expected no content disposition, got %q", got)
+	}
+}
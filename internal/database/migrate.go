@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database/migrations"
+)
+
+var (
+	ErrSchemaBehind = errors.New("database schema is behind")
+
+	setupOnce sync.Once
+	setupErr  error
+)
+
+func setupGoose() error {
+	setupOnce.Do(func() {
+		goose.SetBaseFS(migrations.FS)
+		goose.SetLogger(gooseLogger{})
+		setupErr = goose.SetDialect("postgres")
+	})
+	return setupErr
+}
+
+// Migrate runs a goose command (up, down, status, redo) against the embedded migrations
+func Migrate(db *sql.DB, command string) (err error) {
+	if err = setupGoose(); err != nil {
+		return
+	}
+
+	switch command {
+	case "up", "down", "status", "redo":
+	default:
+		err = fmt.Errorf("unsupported migration command: %s", command)
+		return
+	}
+
+	err = goose.Run(command, db, ".")
+	return
+}
+
+// CheckSchema returns ErrSchemaBehind if the database is missing embedded migrations
+func CheckSchema(db *sql.DB) (err error) {
+	if err = setupGoose(); err != nil {
+		return
+	}
+
+	var current int64
+	if current, err = goose.GetDBVersion(db); err != nil {
+		return
+	}
+
+	var known goose.Migrations
+	if known, err = goose.CollectMigrations(".", 0, goose.MaxVersion); err != nil {
+		return
+	}
+
+	var latest *goose.Migration
+	if latest, err = known.Last(); err != nil {
+		return
+	}
+
+	if current < latest.Version {
+		err = fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaBehind, current, latest.Version)
+	}
+	return
+}
+
+type gooseLogger struct{}
+
+func (gooseLogger) Fatal(v ...interface{}) {
+	zap.S().Fatal(v...)
+}
+
+func (gooseLogger) Fatalf(format string, v ...interface{}) {
+	zap.S().Fatalf(format, v...)
+}
+
+func (gooseLogger) Print(v ...interface{}) {
+	zap.S().Info(v...)
+}
+
+func (gooseLogger) Println(v ...interface{}) {
+	zap.S().Info(v...)
+}
+
+func (gooseLogger) Printf(format string, v ...interface{}) {
+	zap.S().Infof(format, v...)
+}
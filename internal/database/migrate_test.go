@@ -0,0 +1,28 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	if err := setupGoose(); err != nil {
+		t.Fatal(err)
+	}
+
+	known, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(known) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i := 1; i < len(known); i++ {
+		if known[i].Version <= known[i-1].Version {
+			t.Fatalf("migrations out of order: %s after %s", known[i].Source, known[i-1].Source)
+		}
+	}
+}
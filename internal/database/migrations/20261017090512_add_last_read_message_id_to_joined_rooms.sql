@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    ADD COLUMN last_read_message_id BIGINT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    DROP COLUMN last_read_message_id;
+-- +goose StatementEnd
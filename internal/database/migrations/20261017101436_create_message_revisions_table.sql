@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages
+    ADD COLUMN edited_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by CHAR(32);
+
+CREATE TABLE message_revisions (
+   id BIGSERIAL NOT NULL,
+   message_id BIGINT NOT NULL,
+   editor CHAR(32) NOT NULL,
+   created_at TIMESTAMPTZ NOT NULL,
+   message TEXT NOT NULL,
+
+   UNIQUE (id),
+   CONSTRAINT fk_messages_message_id FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_revisions;
+
+ALTER TABLE messages
+    DROP COLUMN edited_at,
+    DROP COLUMN deleted_at,
+    DROP COLUMN deleted_by;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE agents (
+    id BIGSERIAL NOT NULL,
+    username TEXT NOT NULL,
+    display_name TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    session_id CHAR(32) NOT NULL,
+    supervisor BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    UNIQUE (username),
+    UNIQUE (session_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE agents;
+-- +goose StatementEnd
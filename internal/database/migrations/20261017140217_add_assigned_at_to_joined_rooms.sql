@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    ADD COLUMN assigned_at TIMESTAMPTZ;
+
+-- At most one agent is responsible for a room at a time
+CREATE UNIQUE INDEX joined_rooms_assigned_room_id_idx ON joined_rooms (room_id) WHERE assigned_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX joined_rooms_assigned_room_id_idx;
+
+ALTER TABLE joined_rooms
+    DROP COLUMN assigned_at;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE agents
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'offline' CHECK (status IN ('online', 'away', 'offline')),
+    ADD COLUMN status_changed_at TIMESTAMPTZ,
+    ADD COLUMN max_rooms INTEGER NOT NULL DEFAULT 5 CHECK (max_rooms >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE agents
+    DROP COLUMN max_rooms,
+    DROP COLUMN status_changed_at,
+    DROP COLUMN status;
+-- +goose StatementEnd
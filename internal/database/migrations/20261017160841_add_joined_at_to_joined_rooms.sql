@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Unknown for rooms joined before this migration
+ALTER TABLE joined_rooms
+    ADD COLUMN joined_at TIMESTAMPTZ;
+
+ALTER TABLE joined_rooms
+    ALTER COLUMN joined_at SET DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    DROP COLUMN joined_at;
+-- +goose StatementEnd
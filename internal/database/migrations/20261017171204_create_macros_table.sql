@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE agents
+    ADD COLUMN team TEXT NOT NULL DEFAULT 'default';
+
+CREATE TABLE macros (
+    id BIGSERIAL NOT NULL,
+    team TEXT NOT NULL,
+    shortcut TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    UNIQUE (team, shortcut),
+    FOREIGN KEY (created_by) REFERENCES agents (id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE macros;
+
+ALTER TABLE agents
+    DROP COLUMN team;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms
+    ADD COLUMN customer_name TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE rooms
+    DROP COLUMN customer_name;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id BIGSERIAL NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    UNIQUE (name)
+);
+
+CREATE TABLE room_tags (
+    room_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    added_by CHAR(32) NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (room_id, tag_id),
+    CONSTRAINT fk_rooms_room_id FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_tags_tag_id FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX room_tags_tag_id_idx ON room_tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE room_tags;
+DROP TABLE tags;
+-- +goose StatementEnd
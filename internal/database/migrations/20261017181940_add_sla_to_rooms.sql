@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms
+    ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+    ADD COLUMN first_response_warn_at TIMESTAMPTZ,
+    ADD COLUMN first_response_due_at TIMESTAMPTZ,
+    ADD COLUMN first_response_breached_at TIMESTAMPTZ,
+    ADD COLUMN next_response_warn_at TIMESTAMPTZ,
+    ADD COLUMN next_response_due_at TIMESTAMPTZ,
+    ADD COLUMN next_response_breached_at TIMESTAMPTZ;
+
+-- Scanned by the SLA scheduler
+CREATE INDEX rooms_first_response_due_at_idx ON rooms (first_response_due_at) WHERE archived_at IS NULL;
+CREATE INDEX rooms_next_response_due_at_idx ON rooms (next_response_due_at) WHERE archived_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX rooms_next_response_due_at_idx;
+DROP INDEX rooms_first_response_due_at_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN next_response_breached_at,
+    DROP COLUMN next_response_due_at,
+    DROP COLUMN next_response_warn_at,
+    DROP COLUMN first_response_breached_at,
+    DROP COLUMN first_response_due_at,
+    DROP COLUMN first_response_warn_at,
+    DROP COLUMN priority;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE room_ratings (
+    id BIGSERIAL NOT NULL,
+    room_id BIGINT NOT NULL,
+    agent_id BIGINT,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    UNIQUE (room_id),
+    CONSTRAINT fk_rooms_room_id FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_agents_agent_id FOREIGN KEY (agent_id) REFERENCES agents (id) ON DELETE SET NULL
+);
+
+CREATE INDEX room_ratings_agent_id_idx ON room_ratings (agent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE room_ratings;
+-- +goose StatementEnd
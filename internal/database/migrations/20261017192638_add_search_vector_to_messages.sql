@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The simple configuration does no stemming, as conversations are in any language
+ALTER TABLE messages
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+
+CREATE INDEX messages_search_vector_idx ON messages USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX messages_search_vector_idx;
+
+ALTER TABLE messages
+    DROP COLUMN search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms
+    ADD COLUMN transcript_emailed_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE rooms
+    DROP COLUMN transcript_emailed_at;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE attachments (
+    id BIGSERIAL NOT NULL,
+    room_id BIGINT NOT NULL,
+    message_id BIGINT,
+    uploader CHAR(32) NOT NULL,
+    storage_key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    UNIQUE (storage_key),
+    CONSTRAINT fk_rooms_room_id FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE CASCADE,
+    CONSTRAINT fk_messages_message_id FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+);
+
+CREATE INDEX attachments_message_id_idx ON attachments (message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE attachments;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE attachments
+    ADD COLUMN scan_status TEXT NOT NULL DEFAULT 'clean' CHECK (scan_status IN ('clean', 'quarantined')),
+    ADD COLUMN quarantine_reason TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE attachments
+    DROP COLUMN quarantine_reason,
+    DROP COLUMN scan_status;
+-- +goose StatementEnd
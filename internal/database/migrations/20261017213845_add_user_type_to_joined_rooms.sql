@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    ADD COLUMN user_type SMALLINT NOT NULL DEFAULT 0;
+
+-- Only the customer owning a room joins it without support rights
+UPDATE joined_rooms AS jr
+SET user_type = 1
+FROM rooms AS r
+WHERE r.id = jr.room_id AND r.owner != jr.user_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE joined_rooms
+    DROP COLUMN user_type;
+-- +goose StatementEnd
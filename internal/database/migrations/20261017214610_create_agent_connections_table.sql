@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE agent_connections (
+    id BIGSERIAL NOT NULL,
+    session_id CHAR(32) NOT NULL,
+    instance_id TEXT NOT NULL,
+    connected_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (id)
+);
+
+CREATE INDEX agent_connections_session_id_idx ON agent_connections (session_id);
+CREATE INDEX agent_connections_instance_id_idx ON agent_connections (instance_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE agent_connections;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms
+    ADD COLUMN transcript_email_requested_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE rooms
+    DROP COLUMN transcript_email_requested_at;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE room_presence (
+    connection TEXT NOT NULL,
+    room_id BIGINT NOT NULL,
+    participant TEXT NOT NULL,
+    user_type SMALLINT NOT NULL,
+    instance_id TEXT NOT NULL,
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (connection),
+    CONSTRAINT fk_rooms_room_id FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE CASCADE
+);
+
+CREATE INDEX room_presence_room_id_idx ON room_presence (room_id);
+CREATE INDEX room_presence_instance_id_idx ON room_presence (instance_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE room_presence;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sla_breaches (
+    id BIGSERIAL NOT NULL,
+    room_id BIGINT NOT NULL,
+    target TEXT NOT NULL CHECK (target IN ('firstResponse', 'nextResponse')),
+    due_at TIMESTAMPTZ NOT NULL,
+    breached_at TIMESTAMPTZ NOT NULL,
+
+    UNIQUE (id),
+    CONSTRAINT fk_rooms_room_id FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE CASCADE
+);
+
+CREATE INDEX sla_breaches_room_id_idx ON sla_breaches (room_id);
+CREATE INDEX sla_breaches_breached_at_idx ON sla_breaches (breached_at);
+
+-- Only the latest breach of each target is known for existing rooms
+INSERT INTO sla_breaches (room_id, target, due_at, breached_at)
+SELECT id, 'firstResponse', COALESCE(first_response_due_at, first_response_breached_at), first_response_breached_at
+FROM rooms
+WHERE first_response_breached_at IS NOT NULL;
+
+INSERT INTO sla_breaches (room_id, target, due_at, breached_at)
+SELECT id, 'nextResponse', COALESCE(next_response_due_at, next_response_breached_at), next_response_breached_at
+FROM rooms
+WHERE next_response_breached_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sla_breaches;
+-- +goose StatementEnd
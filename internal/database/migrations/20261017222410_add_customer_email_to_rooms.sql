@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rooms
+    ADD COLUMN customer_email TEXT;
+
+-- Looked up when limiting transcript emails per session
+CREATE INDEX rooms_owner_idx ON rooms (owner);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX rooms_owner_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN customer_email;
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS contains all SQL migrations, so they ship with the binary
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	AgentOnline  = "online"
+	AgentAway    = "away"
+	AgentOffline = "offline"
+)
+
+type Agent struct {
+	bun.BaseModel
+
+	ID           uint `bun:",pk,autoincrement"`
+	Username     string
+	DisplayName  string
+	PasswordHash string
+	// Stable session id of the agent, used wherever customers are identified by
+	// their session cookie
+	SessionID  string
+	Supervisor bool
+	CreatedAt  time.Time
+	// One of AgentOnline, AgentAway or AgentOffline. Only online agents are
+	// routed new rooms.
+	Status          string `bun:",nullzero,notnull,default:'offline'"`
+	StatusChangedAt *time.Time
+	// Maximum number of open rooms routed to the agent at once, zero stops routing
+	// to the agent altogether
+	MaxRooms int `bun:",notnull,default:5"`
+	// Agents of a team share macros
+	Team string `bun:",nullzero,notnull,default:'default'"`
+}
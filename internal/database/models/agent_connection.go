@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// AgentConnection is an open websocket connection of an agent to one of the
+// running instances
+type AgentConnection struct {
+	bun.BaseModel
+
+	ID          uint `bun:",pk,autoincrement"`
+	SessionID   string
+	InstanceID  string
+	ConnectedAt time.Time `bun:",nullzero,default:now()"`
+	// Refreshed by the owning instance, stale rows belong to instances which are gone
+	HeartbeatAt time.Time `bun:",nullzero,default:now()"`
+}
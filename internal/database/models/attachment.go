@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	AttachmentClean       = "clean"
+	AttachmentQuarantined = "quarantined"
+)
+
+// Attachment is a file uploaded to a room. It stays unlinked until the uploader
+// sends a message referencing it.
+type Attachment struct {
+	bun.BaseModel
+
+	ID          uint `bun:",pk,autoincrement"`
+	RoomID      uint
+	MessageID   *uint
+	Uploader    string
+	StorageKey  string
+	Filename    string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+	// Quarantined files were rejected by the upload scanners and are never served
+	ScanStatus       string `bun:",nullzero,notnull,default:'clean'"`
+	QuarantineReason *string
+}
@@ -1,11 +1,21 @@
 package models
 
-import "github.com/uptrace/bun"
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
 
 type JoinedRoom struct {
 	bun.BaseModel
 
-	ID     uint `bun:",pk,autoincrement"`
-	UserID string
-	RoomID uint
+	ID                uint `bun:",pk,autoincrement"`
+	UserID            string
+	RoomID            uint
+	LastReadMessageID *uint
+	// User type of the session at the time it joined, see UserTypeCustomer
+	UserType uint
+	// Set on the row of the agent currently responsible for the room
+	AssignedAt *time.Time
+	JoinedAt   *time.Time `bun:",nullzero,default:now()"`
 }
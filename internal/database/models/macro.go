@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Macro is a reply template shared by agents of a team
+type Macro struct {
+	bun.BaseModel
+
+	ID        uint `bun:",pk,autoincrement"`
+	Team      string
+	Shortcut  string
+	Title     string
+	Body      string
+	CreatedBy uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
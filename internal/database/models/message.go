@@ -6,6 +6,15 @@ import (
 	"github.com/uptrace/bun"
 )
 
+const (
+	UserTypeCustomer uint = 0
+	UserTypeSupport  uint = 1
+	// Only visible to support personnel
+	UserTypeInternal uint = 2
+	// Generated by the service itself, such as transfer announcements
+	UserTypeSystem uint = 3
+)
+
 type Message struct {
 	bun.BaseModel
 
@@ -15,4 +24,9 @@ type Message struct {
 	Timestamp time.Time
 	UserType  uint
 	Message   string
+	EditedAt  *time.Time
+	DeletedAt *time.Time
+	DeletedBy *string
+
+	Attachments []Attachment `bun:"rel:has-many,join:id=message_id"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// MessageRevision holds message content replaced by an edit
+type MessageRevision struct {
+	bun.BaseModel
+
+	ID        uint `bun:",pk,autoincrement"`
+	MessageID uint
+	Editor    string
+	CreatedAt time.Time
+	Message   string
+}
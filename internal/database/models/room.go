@@ -13,4 +13,22 @@ type Room struct {
 	Owner      string
 	CreatedAt  time.Time
 	ArchivedAt *time.Time
+	// Optionally given by the customer when opening the room
+	CustomerName *string
+	// Only address transcripts may be emailed to, optionally given when opening the room
+	CustomerEmail *string
+	Priority      string `bun:",nullzero,notnull,default:'normal'"`
+	// Transcripts are emailed at most once per room. Requested is set when the
+	// email is queued and limits retries, Emailed once it was sent.
+	TranscriptEmailRequestedAt *time.Time
+	TranscriptEmailedAt        *time.Time
+
+	// SLA timers. Warn and due times are cleared once support replies, breach
+	// times hold the latest breach, every breach is kept in SLABreach.
+	FirstResponseWarnAt     *time.Time
+	FirstResponseDueAt      *time.Time
+	FirstResponseBreachedAt *time.Time
+	NextResponseWarnAt      *time.Time
+	NextResponseDueAt       *time.Time
+	NextResponseBreachedAt  *time.Time
 }
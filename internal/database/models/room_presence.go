@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// RoomPresence is an active activity subscription of a session to a room
+type RoomPresence struct {
+	bun.BaseModel `bun:"table:room_presence"`
+
+	Connection  string `bun:",pk"`
+	RoomID      uint
+	Participant string
+	UserType    uint
+	InstanceID  string
+	// Refreshed by the owning instance, stale rows belong to instances which are gone
+	HeartbeatAt time.Time `bun:",nullzero,default:now()"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// RoomRating is the satisfaction rating a customer gave after the room was archived
+type RoomRating struct {
+	bun.BaseModel
+
+	ID     uint `bun:",pk,autoincrement"`
+	RoomID uint
+	// Agent who was assigned to the room, if any
+	AgentID   *uint
+	Score     int
+	Comment   *string
+	CreatedAt time.Time
+}
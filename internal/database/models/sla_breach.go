@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SLABreach records every time a room missed one of its SLA targets. The breach
+// columns of the room only keep the latest one.
+type SLABreach struct {
+	bun.BaseModel `bun:"table:sla_breaches"`
+
+	ID     uint `bun:",pk,autoincrement"`
+	RoomID uint
+	// One of sla.TargetFirstResponse or sla.TargetNextResponse
+	Target     string
+	DueAt      time.Time
+	BreachedAt time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type Tag struct {
+	bun.BaseModel
+
+	ID        uint `bun:",pk,autoincrement"`
+	Name      string
+	CreatedAt time.Time
+}
+
+type RoomTag struct {
+	bun.BaseModel
+
+	RoomID  uint `bun:",pk"`
+	TagID   uint `bun:",pk"`
+	AddedBy string
+	AddedAt time.Time
+}
@@ -0,0 +1,13 @@
+package events
+
+import "context"
+
+// Backend transports published events to every attached hub, including the
+// publishing one
+type Backend interface {
+	// Publish sends the event to all listeners
+	Publish(ctx context.Context, evt Event) error
+
+	// Listen delivers received events to the callback until ctx is done
+	Listen(ctx context.Context, deliver func(Event)) error
+}
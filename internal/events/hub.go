@@ -0,0 +1,127 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const subscriptionBuffer = 64
+
+// Event is a single notification published on a topic
+type Event struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Hub fans out events received from the backend to local subscribers
+type Hub struct {
+	backend Backend
+
+	mu   sync.RWMutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func NewHub(backend Backend) *Hub {
+	return &Hub{
+		backend: backend,
+		subs:    make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Run receives events from the backend until ctx is done
+func (h *Hub) Run(ctx context.Context) error {
+	return h.backend.Listen(ctx, h.dispatch)
+}
+
+// Publish encodes data and sends it through the backend to every subscriber of
+// the topic
+func (h *Hub) Publish(ctx context.Context, topic string, eventType string, data interface{}) (err error) {
+	var encoded []byte
+	if encoded, err = json.Marshal(data); err != nil {
+		return
+	}
+
+	err = h.backend.Publish(ctx, Event{
+		Topic: topic,
+		Type:  eventType,
+		Data:  encoded,
+	})
+	return
+}
+
+// Subscribe registers a new subscription for given topics. Subscription must be
+// closed by the caller once it is no longer needed.
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		topics: topics,
+		ch:     make(chan Event, subscriptionBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range topics {
+		subs, ok := h.subs[topic]
+		if !ok {
+			subs = make(map[*Subscription]struct{})
+			h.subs[topic] = subs
+		}
+		subs[sub] = struct{}{}
+	}
+
+	return sub
+}
+
+func (h *Hub) dispatch(evt Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs[evt.Topic] {
+		select {
+		case sub.ch <- evt:
+		default:
+			// Slow consumer, don't block everyone else
+			zap.L().Warn("dropping event for slow subscriber",
+				zap.String("topic", evt.Topic),
+				zap.String("type", evt.Type),
+			)
+		}
+	}
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range sub.topics {
+		subs := h.subs[topic]
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subs, topic)
+		}
+	}
+}
+
+type Subscription struct {
+	hub    *Hub
+	topics []string
+	ch     chan Event
+	once   sync.Once
+}
+
+// Events returns the channel where subscribed events are delivered
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close removes the subscription from the hub
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
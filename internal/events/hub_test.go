@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHubDeliversToTopicSubscribers(t *testing.T) {
+	hub := newTestHub(t)
+
+	sub := hub.Subscribe(RoomTopic(1))
+	defer sub.Close()
+
+	other := hub.Subscribe(RoomTopic(2))
+	defer other.Close()
+
+	if err := hub.Publish(context.Background(), RoomTopic(1), TypeMessageCreated, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-sub.Events():
+		if evt.Type != TypeMessageCreated || string(evt.Data) != `"hello"` {
+			t.Fatalf("unexpected event: %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	select {
+	case evt := <-other.Events():
+		t.Fatalf("received event for wrong topic: %+v", evt)
+	default:
+	}
+}
+
+func TestHubClosedSubscription(t *testing.T) {
+	hub := newTestHub(t)
+
+	sub := hub.Subscribe(RoomTopic(1))
+	sub.Close()
+	sub.Close()
+
+	if err := hub.Publish(context.Background(), RoomTopic(1), TypeMessageCreated, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case evt := <-sub.Events():
+		t.Fatalf("received event after close: %+v", evt)
+	default:
+	}
+
+	if len(hub.subs) != 0 {
+		t.Fatalf("expected no remaining topics, got %d", len(hub.subs))
+	}
+}
+
+func newTestHub(t *testing.T) *Hub {
+	backend := NewMemoryBackend()
+	hub := NewHub(backend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = hub.Run(ctx) }()
+
+	// Wait until the hub is attached to the backend
+	for {
+		backend.mu.RLock()
+		attached := len(backend.listeners) > 0
+		backend.mu.RUnlock()
+
+		if attached {
+			return hub
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
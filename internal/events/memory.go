@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Backend = (*MemoryBackend)(nil)
+
+// MemoryBackend delivers events within a single process
+type MemoryBackend struct {
+	mu        sync.RWMutex
+	listeners map[int]func(Event)
+	nextID    int
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		listeners: make(map[int]func(Event)),
+	}
+}
+
+func (b *MemoryBackend) Publish(ctx context.Context, evt Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, deliver := range b.listeners {
+		deliver(evt)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Listen(ctx context.Context, deliver func(Event)) error {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.listeners[id] = deliver
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.listeners, id)
+		b.mu.Unlock()
+	}()
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	postgresChannel = "helpify_events"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more, larger events are
+	// stored in the event_payloads table and only referenced by the notification
+	postgresMaxPayload = 7999
+	// Stored payloads are kept long enough for every listener to load them
+	postgresPayloadRetention = 5 * time.Minute
+
+	postgresMinBackoff = 1 * time.Second
+	postgresMaxBackoff = 30 * time.Second
+)
+
+var _ Backend = (*PostgresBackend)(nil)
+
+// PostgresBackend delivers events between processes using LISTEN/NOTIFY
+type PostgresBackend struct {
+	db     *sql.DB
+	config *pgx.ConnConfig
+}
+
+// NewPostgresBackend publishes through db and listens on a dedicated connection
+// opened with config
+func NewPostgresBackend(db *sql.DB, config *pgx.ConnConfig) *PostgresBackend {
+	return &PostgresBackend{
+		db:     db,
+		config: config,
+	}
+}
+
+// postgresNotification is either the event itself or a reference to its stored payload
+type postgresNotification struct {
+	Event
+	Ref int64 `json:"ref,omitempty"`
+}
+
+func (b *PostgresBackend) Publish(ctx context.Context, evt Event) (err error) {
+	var payload []byte
+	if payload, err = json.Marshal(postgresNotification{Event: evt}); err != nil {
+		return
+	}
+
+	if len(payload) > postgresMaxPayload {
+		if payload, err = b.store(ctx, payload); err != nil {
+			return
+		}
+	}
+
+	_, err = b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", postgresChannel, string(payload))
+	return
+}
+
+// store saves an oversized payload, returning the notification referencing it
+func (b *PostgresBackend) store(ctx context.Context, payload []byte) (ref []byte, err error) {
+	var notification postgresNotification
+
+	// Expired payloads are cleaned up along the way
+	err = b.db.QueryRowContext(ctx, `
+		WITH expired AS (
+			DELETE FROM event_payloads WHERE created_at < now() - make_interval(secs => $2)
+		)
+		INSERT INTO event_payloads (payload) VALUES ($1) RETURNING id`,
+		string(payload), postgresPayloadRetention.Seconds(),
+	).Scan(&notification.Ref)
+	if err != nil {
+		err = fmt.Errorf("failed to store event payload: %w", err)
+		return
+	}
+
+	return json.Marshal(notification)
+}
+
+func (b *PostgresBackend) Listen(ctx context.Context, deliver func(Event)) error {
+	backoff := postgresMinBackoff
+
+	for {
+		err := b.listen(ctx, deliver, func() {
+			backoff = postgresMinBackoff
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		zap.L().Error("postgres event listener failed, reconnecting",
+			zap.Error(err),
+			zap.Duration("backoff", backoff),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > postgresMaxBackoff {
+			backoff = postgresMaxBackoff
+		}
+	}
+}
+
+func (b *PostgresBackend) listen(ctx context.Context, deliver func(Event), connected func()) (err error) {
+	var conn *pgx.Conn
+	if conn, err = pgx.ConnectConfig(ctx, b.config); err != nil {
+		return
+	}
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{postgresChannel}.Sanitize()); err != nil {
+		return
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var received postgresNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &received); err != nil {
+			zap.L().Warn("dropping malformed event", zap.Error(err))
+			continue
+		}
+
+		if received.Ref != 0 {
+			var payload string
+			err = conn.QueryRow(ctx, "SELECT payload FROM event_payloads WHERE id = $1", received.Ref).Scan(&payload)
+			if errors.Is(err, pgx.ErrNoRows) {
+				zap.L().Warn("dropping expired event", zap.Int64("ref", received.Ref))
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal([]byte(payload), &received); err != nil {
+				zap.L().Warn("dropping malformed event", zap.Error(err))
+				continue
+			}
+		}
+
+		deliver(received.Event)
+	}
+}
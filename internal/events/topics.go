@@ -0,0 +1,45 @@
+package events
+
+import "fmt"
+
+const (
+	TypeMessageCreated  = "message.created"
+	TypeMessageEdited   = "message.edited"
+	TypeMessageDeleted  = "message.deleted"
+	TypeRoomCreated     = "room.created"
+	TypeRoomJoined      = "room.joined"
+	TypeRoomArchived    = "room.archived"
+	TypeRoomReopened    = "room.reopened"
+	TypeRoomAssigned    = "room.assigned"
+	TypeRoomTagged      = "room.tagged"
+	TypeRoomPrioritized = "room.prioritized"
+	TypeMessageRead     = "message.read"
+	TypeTyping          = "chat.typing"
+	TypePresence        = "chat.presence"
+	TypeAgentStatus     = "agent.status"
+	TypeSLAWarning      = "sla.warning"
+	TypeSLABreached     = "sla.breached"
+)
+
+const (
+	// QueueTopic is where changes relevant to support personnel are published
+	QueueTopic = "queue"
+
+	// PresenceTopic carries presence changes of all rooms, so every instance can
+	// keep track of who is connected
+	PresenceTopic = "presence"
+
+	// AgentsTopic carries status changes of support agents
+	AgentsTopic = "agents"
+)
+
+// RoomTopic returns the topic where events of a single room are published
+func RoomTopic(roomID uint) string {
+	return fmt.Sprintf("room:%d", roomID)
+}
+
+// AgentTopic returns the topic where events addressed to a single support agent
+// are published
+func AgentTopic(sessionID string) string {
+	return fmt.Sprintf("agent:%s", sessionID)
+}
@@ -0,0 +1,7 @@
+package jsonrpc
+
+import (
+	"github.com/helpify-project/backend/internal/agents"
+)
+
+type AgentStatus = agents.Status
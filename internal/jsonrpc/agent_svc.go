@@ -0,0 +1,119 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/agents"
+	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/rpc"
+)
+
+func NewAgentService(db *bun.DB, hub *events.Hub) *AgentService {
+	return &AgentService{
+		baseService: baseService{
+			DB:     db,
+			Events: hub,
+		},
+	}
+}
+
+type AgentService struct {
+	baseService
+}
+
+// SetStatus changes the availability of the calling agent
+func (s *AgentService) SetStatus(ctx context.Context, status string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	switch status {
+	case models.AgentOnline, models.AgentAway, models.AgentOffline:
+	default:
+		err = fmt.Errorf("unsupported status: %s", status)
+		return
+	}
+
+	var changed bool
+	if changed, err = agents.UpdateStatus(ctx, s.DB, sid, status); err != nil {
+		return
+	}
+
+	ok = true
+	if changed {
+		agents.Publish(ctx, s.DB, s.Events, sid)
+	}
+	return
+}
+
+// SetCapacity changes how many open rooms may be routed to the calling agent at once
+func (s *AgentService) SetCapacity(ctx context.Context, maxRooms int) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	if maxRooms < 0 {
+		err = fmt.Errorf("capacity must not be negative")
+		return
+	}
+
+	_, err = s.DB.NewUpdate().
+		Model((*models.Agent)(nil)).
+		Set("max_rooms = ?", maxRooms).
+		Where("session_id = ?", sid).
+		Exec(ctx)
+	if err != nil {
+		return
+	}
+
+	ok = true
+	agents.Publish(ctx, s.DB, s.Events, sid)
+	return
+}
+
+// Status returns the status of the calling agent
+func (s *AgentService) Status(ctx context.Context) (status AgentStatus, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	var entry agents.Entry
+	if err = agents.Select(s.DB, &entry).Where("a.session_id = ?", sid).Scan(ctx); err != nil {
+		return
+	}
+
+	status = entry.ToStatus()
+	return
+}
+
+// List returns the status of all agents
+func (s *AgentService) List(ctx context.Context) (statuses []AgentStatus, err error) {
+	statuses = make([]AgentStatus, 0)
+
+	var entries []agents.Entry
+	if err = agents.Select(s.DB, &entries).Order("a.username").Scan(ctx); err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		statuses = append(statuses, entry.ToStatus())
+	}
+	return
+}
+
+// Statuses pushes status and capacity changes of all agents
+func (s *AgentService) Statuses(ctx context.Context) (sub *rpc.Subscription, err error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		err = rpc.ErrNotificationsUnsupported
+		return
+	}
+
+	sub = notifier.CreateSubscription()
+	agentEvents := s.Events.Subscribe(events.AgentsTopic)
+
+	go forwardEvents(notifier, sub, agentEvents, func(evt events.Event) (interface{}, bool) {
+		return evt.Data, true
+	})
+
+	return
+}
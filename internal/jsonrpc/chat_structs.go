@@ -11,14 +11,66 @@ import (
 type InputMessage struct {
 	RoomID  string `json:"roomId"`
 	Message string `json:"message"`
+	// IDs of files uploaded to the room beforehand
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// Sent by client, all fields are optional
+type HistoryQuery struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Limit  int    `json:"limit"`
+}
+
+// Messages in ascending order. Before and After are cursors for loading older and
+// newer messages respectively.
+type HistoryPage struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"hasMore"`
+	Before   string    `json:"before,omitempty"`
+	After    string    `json:"after,omitempty"`
 }
 
 type Message struct {
-	ID        string    `json:"id"`
-	Message   string    `json:"message"`
-	RoomID    string    `json:"roomId"`
-	UserType  uint      `json:"userType"`
-	Timestamp time.Time `json:"timestamp"`
+	ID        string     `json:"id"`
+	Message   string     `json:"message"`
+	RoomID    string     `json:"roomId"`
+	UserType  uint       `json:"userType"`
+	Timestamp time.Time  `json:"timestamp"`
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	Deleted   bool       `json:"deleted"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url,omitempty"`
+	// Either "clean" or "quarantined", rejected files cannot be downloaded
+	Status           string `json:"status"`
+	QuarantineReason string `json:"quarantineReason,omitempty"`
+}
+
+func AttachmentFromModel(attachment models.Attachment) (a Attachment) {
+	a = Attachment{
+		ID:          fmt.Sprint(attachment.ID),
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Status:      attachment.ScanStatus,
+	}
+
+	if attachment.ScanStatus == models.AttachmentQuarantined {
+		if attachment.QuarantineReason != nil {
+			a.QuarantineReason = *attachment.QuarantineReason
+		}
+	} else {
+		a.URL = fmt.Sprintf("/chat/attachments/%d", attachment.ID)
+	}
+	return
 }
 
 func MessageFromModel(msg models.Message) (m Message) {
@@ -32,4 +84,44 @@ func (m *Message) FromModel(msg models.Message) {
 	m.RoomID = fmt.Sprint(msg.RoomID)
 	m.UserType = msg.UserType
 	m.Timestamp = msg.Timestamp
+	m.EditedAt = msg.EditedAt
+
+	// Content of deleted messages is only retained for auditing
+	if msg.DeletedAt != nil {
+		m.Message = ""
+		m.Deleted = true
+		return
+	}
+
+	for _, attachment := range msg.Attachments {
+		m.Attachments = append(m.Attachments, AttachmentFromModel(attachment))
+	}
+}
+
+const (
+	ActivityTyping   = "typing"
+	ActivityPresence = "presence"
+)
+
+// Ephemeral room activity pushed to activity subscribers, never persisted
+type Activity struct {
+	Type        string `json:"type"`
+	RoomID      string `json:"roomId"`
+	Participant string `json:"participant"`
+	UserType    uint   `json:"userType"`
+	Typing      bool   `json:"typing"`
+	Online      bool   `json:"online"`
+}
+
+type Participant struct {
+	ID       string `json:"id"`
+	UserType uint   `json:"userType"`
+}
+
+// Latest message read by a room participant
+type ReadReceipt struct {
+	RoomID      string `json:"roomId"`
+	Participant string `json:"participant"`
+	UserType    uint   `json:"userType"`
+	MessageID   string `json:"messageId"`
 }
@@ -3,24 +3,43 @@ package jsonrpc
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/uptrace/bun"
+	"go.uber.org/zap"
 
 	"github.com/helpify-project/backend/internal/cctx"
 	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/macros"
+	"github.com/helpify-project/backend/internal/rpc"
+	"github.com/helpify-project/backend/internal/sla"
 )
 
-func NewChatService(db *bun.DB) *ChatService {
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+func NewChatService(db *bun.DB, hub *events.Hub, timers *sla.Timers) *ChatService {
 	return &ChatService{
 		baseService: baseService{
-			DB: db,
+			DB:     db,
+			Events: hub,
 		},
+		presence: newPresenceTracker(db, hub),
+		timers:   timers,
 	}
 }
 
 type ChatService struct {
 	baseService
+
+	presence *presenceTracker
+	timers   *sla.Timers
 }
 
 func (s *ChatService) Send(ctx context.Context, input InputMessage) (msg Message, err error) {
@@ -30,38 +49,126 @@ func (s *ChatService) Send(ctx context.Context, input InputMessage) (msg Message
 
 	// Find the room
 	var room models.Room
-	//var inRoom bool
-	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
-		if room, err = s.findRoom(ctx, input.RoomID); err != nil {
-			return
-		}
+	if room, err = s.findRoom(ctx, input.RoomID); err != nil {
+		return
+	}
 
-		/*
-			if inRoom, err = s.inRoom(ctx, sid, input.RoomID); err != nil {
-				return
-			}
-		*/
+	if room.ArchivedAt != nil {
+		err = errRoomArchived
+		return
+	}
 
+	if input.Message == "" && len(input.Attachments) == 0 {
+		err = fmt.Errorf("message must have text or attachments")
 		return
-	})
+	}
 
-	/*
-		if !inRoom {
-			err = fmt.Errorf("not member of given room")
+	var attachmentIDs []uint
+	for _, id := range input.Attachments {
+		var attachmentID uint64
+		if attachmentID, err = strconv.ParseUint(id, 10, 64); err != nil {
+			err = fmt.Errorf("invalid attachment id: %s", id)
 			return
 		}
-	*/
+		attachmentIDs = append(attachmentIDs, uint(attachmentID))
+	}
 
 	dbMsg := models.Message{
 		RoomID:    room.ID,
 		Sender:    sid,
 		Timestamp: now,
 		Message:   input.Message,
-		UserType:  0,
+		UserType:  sessionUserType(supportPersonnel),
 	}
 
-	if supportPersonnel {
-		dbMsg.UserType = 1
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) (err error) {
+		if _, err = tx.NewInsert().Model(&dbMsg).Exec(ctx); err != nil {
+			return
+		}
+
+		if len(attachmentIDs) == 0 {
+			return
+		}
+
+		// Only own uploads of this room can be attached, and only once
+		_, err = tx.NewUpdate().
+			Model((*models.Attachment)(nil)).
+			Set("message_id = ?", dbMsg.ID).
+			Where("id IN (?)", bun.In(attachmentIDs)).
+			Where("room_id = ?", room.ID).
+			Where("uploader = ?", sid).
+			Where("message_id IS NULL").
+			Returning("*").
+			Exec(ctx, &dbMsg.Attachments)
+		if err != nil {
+			return
+		}
+
+		if len(dbMsg.Attachments) != len(attachmentIDs) {
+			err = fmt.Errorf("unknown attachment")
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	if err := s.timers.MessageSent(ctx, s.DB, dbMsg); err != nil {
+		zap.L().Error("failed to update sla timers", zap.Error(err))
+	}
+
+	msg = s.publishMessage(ctx, dbMsg)
+	return
+}
+
+// SendMacro renders a macro of the agent's team for the room and sends it as a
+// regular message
+func (s *ChatService) SendMacro(ctx context.Context, roomID string, macroID string) (msg Message, err error) {
+	var macro models.Macro
+	if macro, err = s.findTeamMacro(ctx, s.DB, macroID); err != nil {
+		return
+	}
+
+	var agent models.Agent
+	if agent, err = s.currentAgent(ctx); err != nil {
+		return
+	}
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	values := map[string]string{
+		macros.RoomID:    fmt.Sprint(room.ID),
+		macros.AgentName: agent.DisplayName,
+	}
+	if room.CustomerName != nil {
+		values[macros.CustomerName] = *room.CustomerName
+	}
+
+	msg, err = s.Send(ctx, InputMessage{
+		RoomID:  roomID,
+		Message: macros.Render(macro.Body, values),
+	})
+	return
+}
+
+// Note stores an internal message, which only support personnel get to see
+func (s *ChatService) Note(ctx context.Context, roomID string, text string) (msg Message, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	dbMsg := models.Message{
+		RoomID:    room.ID,
+		Sender:    sid,
+		Timestamp: time.Now(),
+		Message:   text,
+		UserType:  models.UserTypeInternal,
 	}
 
 	_, err = s.DB.NewInsert().
@@ -71,60 +178,497 @@ func (s *ChatService) Send(ctx context.Context, input InputMessage) (msg Message
 		return
 	}
 
-	msg.FromModel(dbMsg)
+	msg = s.publishMessage(ctx, dbMsg)
 	return
 }
 
-func (s *ChatService) History(ctx context.Context, roomID string) (messages []Message, err error) {
-	sid := ctx.Value(cctx.SessionID).(string)
-	messages = make([]Message, 0)
+// History returns a page of room messages. Without cursors the most recent
+// messages are returned; with only after set, paging continues towards newer ones.
+// Internal messages are only returned to support personnel.
+func (s *ChatService) History(ctx context.Context, roomID string, query *HistoryQuery) (page HistoryPage, err error) {
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+	page.Messages = make([]Message, 0)
+
+	if query == nil {
+		query = &HistoryQuery{}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	} else if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	var before, after uint
+	if query.Before != "" {
+		if before, err = decodeMessageCursor(query.Before); err != nil {
+			return
+		}
+	}
+	if query.After != "" {
+		if after, err = decodeMessageCursor(query.After); err != nil {
+			return
+		}
+	}
 
 	// Find the room
 	var room models.Room
-	var inRoom bool
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	var dbMessages []models.Message
+	selectQuery := s.DB.NewSelect().
+		Model(&dbMessages).
+		Relation("Attachments").
+		Where("room_id = ?", room.ID).
+		Limit(limit + 1)
+
+	if !supportPersonnel {
+		selectQuery = selectQuery.Where("user_type != ?", models.UserTypeInternal)
+	}
+	if before > 0 {
+		selectQuery = selectQuery.Where("id < ?", before)
+	}
+	if after > 0 {
+		selectQuery = selectQuery.Where("id > ?", after)
+	}
+
+	// Walk forward from the after cursor, otherwise backwards from the newest message
+	forward := after > 0
+	if forward {
+		selectQuery = selectQuery.Order("id ASC")
+	} else {
+		selectQuery = selectQuery.Order("id DESC")
+	}
+
+	if err = selectQuery.Scan(ctx); err != nil {
+		return
+	}
+
+	if len(dbMessages) > limit {
+		page.HasMore = true
+		dbMessages = dbMessages[:limit]
+	}
+
+	if !forward {
+		for i, j := 0, len(dbMessages)-1; i < j; i, j = i+1, j-1 {
+			dbMessages[i], dbMessages[j] = dbMessages[j], dbMessages[i]
+		}
+	}
+
+	for _, msg := range dbMessages {
+		page.Messages = append(page.Messages, MessageFromModel(msg))
+	}
+
+	if len(dbMessages) > 0 {
+		page.Before = encodeMessageCursor(dbMessages[0].ID)
+		page.After = encodeMessageCursor(dbMessages[len(dbMessages)-1].ID)
+	}
+
+	return
+}
+
+// Messages pushes new, edited and deleted messages of given room to the subscriber.
+// Internal messages and SLA alerts are only pushed to support personnel.
+func (s *ChatService) Messages(ctx context.Context, roomID string) (sub *rpc.Subscription, err error) {
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		err = rpc.ErrNotificationsUnsupported
+		return
+	}
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	sub = notifier.CreateSubscription()
+	roomEvents := s.Events.Subscribe(events.RoomTopic(room.ID))
+
+	go forwardEvents(notifier, sub, roomEvents, func(evt events.Event) (interface{}, bool) {
+		switch evt.Type {
+		case events.TypeMessageCreated, events.TypeMessageEdited, events.TypeMessageDeleted:
+		case events.TypeSLAWarning, events.TypeSLABreached:
+			return evt.Data, supportPersonnel
+		default:
+			return nil, false
+		}
+
+		if supportPersonnel {
+			return evt.Data, true
+		}
+
+		var msg Message
+		if err := json.Unmarshal(evt.Data, &msg); err != nil {
+			zap.L().Warn("dropping malformed message event", zap.Error(err))
+			return nil, false
+		}
+		return evt.Data, msg.UserType != models.UserTypeInternal
+	})
+
+	return
+}
+
+// Edit replaces the message content, retaining the previous version. Only the
+// original sender or a supervisor may edit a message.
+func (s *ChatService) Edit(ctx context.Context, messageID string, text string) (msg Message, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	now := time.Now()
+
+	var dbMsg models.Message
 	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
-		if room, err = s.findRoom(ctx, roomID); err != nil {
+		if dbMsg, err = s.findOwnMessage(ctx, tx, messageID); err != nil {
+			return
+		}
+
+		var archived bool
+		archived, err = tx.NewSelect().
+			Model((*models.Room)(nil)).
+			Where("id = ?", dbMsg.RoomID).
+			Where("archived_at IS NOT NULL").
+			Exists(ctx)
+		if err != nil {
+			return
+		} else if archived {
+			err = errRoomArchived
+			return
+		}
+
+		revision := models.MessageRevision{
+			MessageID: dbMsg.ID,
+			Editor:    sid,
+			CreatedAt: now,
+			Message:   dbMsg.Message,
+		}
+
+		if _, err = tx.NewInsert().Model(&revision).Exec(ctx); err != nil {
 			return
 		}
 
-		if inRoom, err = s.inRoom(ctx, sid, roomID); err != nil {
+		dbMsg.Message = text
+		dbMsg.EditedAt = &now
+
+		_, err = tx.NewUpdate().
+			Model(&dbMsg).
+			Column("message", "edited_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
 			return
 		}
 
+		// Subscribers replace the whole message, attachments included
+		err = tx.NewSelect().
+			Model(&dbMsg.Attachments).
+			Where("message_id = ?", dbMsg.ID).
+			Order("id").
+			Scan(ctx)
 		return
 	})
+	if err != nil {
+		return
+	}
 
-	if !inRoom {
-		//err = fmt.Errorf("not member of given room")
-		//return
+	msg.FromModel(dbMsg)
+
+	if err := s.Events.Publish(ctx, events.RoomTopic(dbMsg.RoomID), events.TypeMessageEdited, msg); err != nil {
+		zap.L().Error("failed to publish edited message", zap.Error(err))
 	}
 
-	var dbMessages []models.Message
+	return
+}
+
+// Delete turns the message into a tombstone. Only the original sender or a
+// supervisor may delete a message.
+func (s *ChatService) Delete(ctx context.Context, messageID string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	now := time.Now()
+
+	var dbMsg models.Message
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		if dbMsg, err = s.findOwnMessage(ctx, tx, messageID); err != nil {
+			return
+		}
+
+		dbMsg.DeletedAt = &now
+		dbMsg.DeletedBy = &sid
+
+		_, err = tx.NewUpdate().
+			Model(&dbMsg).
+			Column("deleted_at", "deleted_by").
+			WherePK().
+			Exec(ctx)
+
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	ok = true
+
+	if err := s.Events.Publish(ctx, events.RoomTopic(dbMsg.RoomID), events.TypeMessageDeleted, MessageFromModel(dbMsg)); err != nil {
+		zap.L().Error("failed to publish deleted message", zap.Error(err))
+	}
+
+	return
+}
+
+// findOwnMessage locks a message that the session is allowed to modify
+func (s *ChatService) findOwnMessage(ctx context.Context, tx bun.Tx, messageID string) (msg models.Message, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	supervisor := ctx.Value(cctx.Supervisor).(bool)
+
+	var intMessageID uint64
+	if intMessageID, err = strconv.ParseUint(messageID, 10, 64); err != nil {
+		return
+	}
+
+	err = tx.NewSelect().
+		Model(&msg).
+		Where("id = ?", intMessageID).
+		For("UPDATE").
+		Scan(ctx)
+	if err != nil {
+		return
+	}
+
+	if !supervisor && msg.Sender != sid {
+		err = fmt.Errorf("can only modify your own messages")
+		return
+	}
+
+	if msg.DeletedAt != nil {
+		err = fmt.Errorf("message has been deleted")
+		return
+	}
+
+	return
+}
+
+// Typing notifies other room members whether the user is currently typing
+func (s *ChatService) Typing(ctx context.Context, roomID string, typing bool) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	activity := Activity{
+		Type:        ActivityTyping,
+		RoomID:      fmt.Sprint(room.ID),
+		Participant: participantID(sid),
+		UserType:    sessionUserType(supportPersonnel),
+		Typing:      typing,
+	}
+
+	err = s.Events.Publish(ctx, events.RoomTopic(room.ID), events.TypeTyping, activity)
+	ok = err == nil
+	return
+}
+
+// Presence returns participants currently connected to the room
+func (s *ChatService) Presence(ctx context.Context, roomID string) (participants []Participant, err error) {
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	participants, err = s.presence.Participants(ctx, room.ID)
+	return
+}
+
+// Activity pushes typing and presence changes of other room members to the
+// subscriber. The subscriber is considered present in the room for as long as
+// the subscription is active.
+func (s *ChatService) Activity(ctx context.Context, roomID string) (sub *rpc.Subscription, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		err = rpc.ErrNotificationsUnsupported
+		return
+	}
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	sub = notifier.CreateSubscription()
+	roomEvents := s.Events.Subscribe(events.RoomTopic(room.ID), events.PresenceTopic)
+
+	self := Activity{
+		Type:        ActivityPresence,
+		RoomID:      fmt.Sprint(room.ID),
+		Participant: participantID(sid),
+		UserType:    sessionUserType(supportPersonnel),
+		Online:      true,
+	}
+	s.presence.Announce(ctx, string(sub.ID), self)
+
+	go func() {
+		defer func() {
+			// Connection is gone or the subscription was cancelled
+			self.Online = false
+			s.presence.Announce(context.Background(), string(sub.ID), self)
+		}()
+
+		forwardEvents(notifier, sub, roomEvents, func(evt events.Event) (interface{}, bool) {
+			if evt.Type != events.TypeTyping && evt.Type != events.TypePresence {
+				return nil, false
+			}
+
+			var change presenceChange
+			if err := json.Unmarshal(evt.Data, &change); err != nil {
+				zap.L().Warn("dropping malformed activity", zap.Error(err))
+				return nil, false
+			}
+
+			if change.RoomID != self.RoomID || change.Participant == self.Participant {
+				return nil, false
+			}
+
+			return change.Activity, true
+		})
+	}()
+
+	return
+}
+
+// MarkRead marks messages of the room up to and including given message as read
+func (s *ChatService) MarkRead(ctx context.Context, roomID string, messageID string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	var intMessageID uint64
+	if intMessageID, err = strconv.ParseUint(messageID, 10, 64); err != nil {
+		return
+	}
+
+	existsQuery := s.DB.NewSelect().
+		Model((*models.Message)(nil)).
+		Where("id = ?", intMessageID).
+		Where("room_id = ?", room.ID)
+	if !supportPersonnel {
+		existsQuery = existsQuery.Where("user_type != ?", models.UserTypeInternal)
+	}
+
+	var exists bool
+	exists, err = existsQuery.Exists(ctx)
+	if err != nil {
+		return
+	} else if !exists {
+		err = fmt.Errorf("message not found in given room")
+		return
+	}
+
+	// Never move the marker backwards
+	res, err := s.DB.NewUpdate().
+		Model((*models.JoinedRoom)(nil)).
+		Set("last_read_message_id = ?", intMessageID).
+		Where("room_id = ?", room.ID).
+		Where("user_id = ?", sid).
+		Where("COALESCE(last_read_message_id, 0) < ?", intMessageID).
+		Exec(ctx)
+	if err != nil {
+		return
+	}
+
+	ok = true
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return
+	}
+
+	receipt := ReadReceipt{
+		RoomID:      fmt.Sprint(room.ID),
+		Participant: participantID(sid),
+		UserType:    sessionUserType(supportPersonnel),
+		MessageID:   fmt.Sprint(intMessageID),
+	}
+
+	if err := s.Events.Publish(ctx, events.RoomTopic(room.ID), events.TypeMessageRead, receipt); err != nil {
+		zap.L().Error("failed to publish read receipt", zap.Error(err))
+	}
+
+	return
+}
+
+// ReadState returns the latest read message of every room participant
+func (s *ChatService) ReadState(ctx context.Context, roomID string) (receipts []ReadReceipt, err error) {
+	receipts = make([]ReadReceipt, 0)
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	var joinedRooms []models.JoinedRoom
 	err = s.DB.NewSelect().
-		Model(&dbMessages).
+		Model(&joinedRooms).
 		Where("room_id = ?", room.ID).
+		Where("last_read_message_id IS NOT NULL").
 		Scan(ctx)
 	if err != nil {
 		return
 	}
 
-	for _, msg := range dbMessages {
-		messages = append(messages, MessageFromModel(msg))
+	for _, joined := range joinedRooms {
+		receipts = append(receipts, ReadReceipt{
+			RoomID:      fmt.Sprint(room.ID),
+			Participant: participantID(joined.UserID),
+			UserType:    joined.UserType,
+			MessageID:   fmt.Sprint(*joined.LastReadMessageID),
+		})
 	}
 
 	return
 }
 
-/*
-func (s *ChatService) NewChatMessages(ctx context.Context) (sub *rpc.Subscription, err error) {
+// Receipts pushes read receipts of other room members to the subscriber
+func (s *ChatService) Receipts(ctx context.Context, roomID string) (sub *rpc.Subscription, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		err = rpc.ErrNotificationsUnsupported
 		return
 	}
 
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
 	sub = notifier.CreateSubscription()
+	roomEvents := s.Events.Subscribe(events.RoomTopic(room.ID))
+	self := participantID(sid)
+
+	go forwardEvents(notifier, sub, roomEvents, func(evt events.Event) (interface{}, bool) {
+		if evt.Type != events.TypeMessageRead {
+			return nil, false
+		}
+
+		var receipt ReadReceipt
+		if err := json.Unmarshal(evt.Data, &receipt); err != nil {
+			zap.L().Warn("dropping malformed read receipt", zap.Error(err))
+			return nil, false
+		}
+
+		return receipt, receipt.Participant != self
+	})
 
 	return
 }
-*/
@@ -2,15 +2,24 @@ package jsonrpc
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
 	"github.com/uptrace/bun"
+	"go.uber.org/zap"
 
+	"github.com/helpify-project/backend/internal/cctx"
 	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/rpc"
 )
 
+// Returned when trying to change the conversation of an archived room
+var errRoomArchived = fmt.Errorf("room is archived")
+
 type baseService struct {
-	DB *bun.DB
+	DB     *bun.DB
+	Events *events.Hub
 }
 
 func (s *baseService) findRoom(ctx context.Context, roomID string) (room models.Room, err error) {
@@ -40,3 +49,68 @@ func (s *baseService) inRoom(ctx context.Context, sid string, roomID string) (ok
 
 	return
 }
+
+// findOwnRoom finds the room, failing if it was not created by given session
+func (s *baseService) findOwnRoom(ctx context.Context, sid string, roomID string) (room models.Room, err error) {
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	if room.Owner != sid {
+		err = fmt.Errorf("can only interact with your own rooms")
+	}
+	return
+}
+
+// currentAgent finds the agent behind a support session
+func (s *baseService) currentAgent(ctx context.Context) (agent models.Agent, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	err = s.DB.NewSelect().
+		Model(&agent).
+		Where("session_id = ?", sid).
+		Scan(ctx)
+	return
+}
+
+func sessionUserType(supportPersonnel bool) uint {
+	if supportPersonnel {
+		return models.UserTypeSupport
+	}
+	return models.UserTypeCustomer
+}
+
+// publishMessage announces a newly stored message to the room
+func (s *baseService) publishMessage(ctx context.Context, dbMsg models.Message) (msg Message) {
+	msg.FromModel(dbMsg)
+
+	if err := s.Events.Publish(ctx, events.RoomTopic(dbMsg.RoomID), events.TypeMessageCreated, msg); err != nil {
+		zap.L().Error("failed to publish new message", zap.Error(err))
+	}
+	return
+}
+
+// forwardEvents pushes events to the RPC subscription until it is cancelled or the
+// connection goes away. forward returns the notification payload, or false to skip
+// the event.
+func forwardEvents(notifier *rpc.Notifier, sub *rpc.Subscription, source *events.Subscription, forward func(evt events.Event) (interface{}, bool)) {
+	defer source.Close()
+
+	for {
+		select {
+		case evt := <-source.Events():
+			data, ok := forward(evt)
+			if !ok {
+				continue
+			}
+
+			if err := notifier.Notify(sub.ID, data); err != nil {
+				zap.L().Debug("failed to notify subscriber", zap.Error(err))
+			}
+		case <-sub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package jsonrpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const messageCursorPrefix = "msg:"
+
+// encodeMessageCursor returns an opaque cursor pointing at given message
+func encodeMessageCursor(messageID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(messageCursorPrefix + strconv.FormatUint(uint64(messageID), 10)))
+}
+
+func decodeMessageCursor(cursor string) (messageID uint, err error) {
+	var decoded []byte
+	if decoded, err = base64.RawURLEncoding.DecodeString(cursor); err != nil {
+		err = fmt.Errorf("invalid cursor")
+		return
+	}
+
+	value := string(decoded)
+	if !strings.HasPrefix(value, messageCursorPrefix) {
+		err = fmt.Errorf("invalid cursor")
+		return
+	}
+
+	var parsed uint64
+	if parsed, err = strconv.ParseUint(strings.TrimPrefix(value, messageCursorPrefix), 10, 64); err != nil {
+		err = fmt.Errorf("invalid cursor")
+		return
+	}
+
+	messageID = uint(parsed)
+	return
+}
@@ -0,0 +1,50 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/helpify-project/backend/internal/database"
+)
+
+// testDB returns a database migrated into a schema of its own. Tests using it are
+// skipped unless HELPIFY_TEST_POSTGRES_URI is set.
+func testDB(t *testing.T) *bun.DB {
+	t.Helper()
+
+	uri := os.Getenv("HELPIFY_TEST_POSTGRES_URI")
+	if uri == "" {
+		t.Skip("HELPIFY_TEST_POSTGRES_URI is not set")
+	}
+
+	config, err := pgx.ParseConfig(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := stdlib.OpenDB(*config)
+	t.Cleanup(func() { _ = admin.Close() })
+
+	schema := fmt.Sprintf("helpify_test_%d", time.Now().UnixNano())
+	if _, err = admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	config.RuntimeParams["search_path"] = schema
+	sqldb := stdlib.OpenDB(*config)
+	if err = database.Migrate(sqldb, "up"); err != nil {
+		t.Fatal(err)
+	}
+
+	db := bun.NewDB(sqldb, pgdialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
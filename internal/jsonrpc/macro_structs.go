@@ -0,0 +1,38 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+// Sent by client
+type MacroInput struct {
+	Shortcut string `json:"shortcut"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+type Macro struct {
+	ID        string    `json:"id"`
+	Shortcut  string    `json:"shortcut"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func MacroFromModel(macro models.Macro) (m Macro) {
+	m.FromModel(macro)
+	return
+}
+
+func (m *Macro) FromModel(macro models.Macro) {
+	m.ID = fmt.Sprint(macro.ID)
+	m.Shortcut = macro.Shortcut
+	m.Title = macro.Title
+	m.Body = macro.Body
+	m.CreatedAt = macro.CreatedAt
+	m.UpdatedAt = macro.UpdatedAt
+}
@@ -0,0 +1,210 @@
+package jsonrpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/macros"
+)
+
+var macroShortcutPattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func NewMacroService(db *bun.DB, hub *events.Hub) *MacroService {
+	return &MacroService{
+		baseService: baseService{
+			DB:     db,
+			Events: hub,
+		},
+	}
+}
+
+// MacroService manages reply templates shared within the team of the calling agent
+type MacroService struct {
+	baseService
+}
+
+func (s *MacroService) List(ctx context.Context) (list []Macro, err error) {
+	list = make([]Macro, 0)
+
+	var agent models.Agent
+	if agent, err = s.currentAgent(ctx); err != nil {
+		return
+	}
+
+	var dbMacros []models.Macro
+	err = s.DB.NewSelect().
+		Model(&dbMacros).
+		Where("team = ?", agent.Team).
+		Order("shortcut ASC").
+		Scan(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, macro := range dbMacros {
+		list = append(list, MacroFromModel(macro))
+	}
+	return
+}
+
+func (s *MacroService) Get(ctx context.Context, macroID string) (macro Macro, err error) {
+	var dbMacro models.Macro
+	if dbMacro, err = s.findTeamMacro(ctx, s.DB, macroID); err != nil {
+		return
+	}
+
+	macro.FromModel(dbMacro)
+	return
+}
+
+func (s *MacroService) Create(ctx context.Context, input MacroInput) (macro Macro, err error) {
+	var agent models.Agent
+	if agent, err = s.currentAgent(ctx); err != nil {
+		return
+	}
+
+	if input, err = validateMacro(input); err != nil {
+		return
+	}
+
+	now := time.Now()
+	dbMacro := models.Macro{
+		Team:      agent.Team,
+		Shortcut:  input.Shortcut,
+		Title:     input.Title,
+		Body:      input.Body,
+		CreatedBy: agent.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		if err = ensureShortcutFree(ctx, tx, agent.Team, input.Shortcut, 0); err != nil {
+			return
+		}
+
+		_, err = tx.NewInsert().
+			Model(&dbMacro).
+			Exec(ctx)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	macro.FromModel(dbMacro)
+	return
+}
+
+func (s *MacroService) Update(ctx context.Context, macroID string, input MacroInput) (macro Macro, err error) {
+	if input, err = validateMacro(input); err != nil {
+		return
+	}
+
+	var dbMacro models.Macro
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		if dbMacro, err = s.findTeamMacro(ctx, tx, macroID); err != nil {
+			return
+		}
+
+		if err = ensureShortcutFree(ctx, tx, dbMacro.Team, input.Shortcut, dbMacro.ID); err != nil {
+			return
+		}
+
+		dbMacro.Shortcut = input.Shortcut
+		dbMacro.Title = input.Title
+		dbMacro.Body = input.Body
+		dbMacro.UpdatedAt = time.Now()
+
+		_, err = tx.NewUpdate().
+			Model(&dbMacro).
+			Column("shortcut", "title", "body", "updated_at").
+			WherePK().
+			Exec(ctx)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	macro.FromModel(dbMacro)
+	return
+}
+
+func (s *MacroService) Delete(ctx context.Context, macroID string) (ok bool, err error) {
+	var dbMacro models.Macro
+	if dbMacro, err = s.findTeamMacro(ctx, s.DB, macroID); err != nil {
+		return
+	}
+
+	_, err = s.DB.NewDelete().
+		Model(&dbMacro).
+		WherePK().
+		Exec(ctx)
+
+	ok = err == nil
+	return
+}
+
+// findTeamMacro finds a macro belonging to the team of the calling agent
+func (s *baseService) findTeamMacro(ctx context.Context, db bun.IDB, macroID string) (macro models.Macro, err error) {
+	var agent models.Agent
+	if agent, err = s.currentAgent(ctx); err != nil {
+		return
+	}
+
+	var intMacroID uint64
+	if intMacroID, err = strconv.ParseUint(macroID, 10, 64); err != nil {
+		return
+	}
+
+	err = db.NewSelect().
+		Model(&macro).
+		Where("id = ?", intMacroID).
+		Where("team = ?", agent.Team).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("macro not found")
+	}
+	return
+}
+
+func ensureShortcutFree(ctx context.Context, tx bun.Tx, team string, shortcut string, exceptID uint) (err error) {
+	var taken bool
+	taken, err = tx.NewSelect().
+		Model((*models.Macro)(nil)).
+		Where("team = ?", team).
+		Where("shortcut = ?", shortcut).
+		Where("id != ?", exceptID).
+		Exists(ctx)
+	if err == nil && taken {
+		err = fmt.Errorf("shortcut is already in use")
+	}
+	return
+}
+
+func validateMacro(input MacroInput) (MacroInput, error) {
+	input.Shortcut = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input.Shortcut), "/"))
+	input.Title = strings.TrimSpace(input.Title)
+
+	if !macroShortcutPattern.MatchString(input.Shortcut) {
+		return input, fmt.Errorf("shortcut may only contain letters, digits, dashes and underscores")
+	}
+	if input.Body == "" {
+		return input, fmt.Errorf("body must not be empty")
+	}
+	if err := macros.Validate(input.Body); err != nil {
+		return input, err
+	}
+	return input, nil
+}
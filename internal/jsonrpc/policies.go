@@ -0,0 +1,210 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/rpc"
+)
+
+// roomParam extracts the room id from a positional call parameter
+type roomParam func(params []interface{}) string
+
+// roomArg reads the room id from the parameter at given index, which is either the
+// room id itself or an input struct carrying it
+func roomArg(index int) roomParam {
+	return func(params []interface{}) string {
+		if index >= len(params) {
+			return ""
+		}
+
+		switch param := params[index].(type) {
+		case string:
+			return param
+		case InputMessage:
+			return param.RoomID
+		}
+		return ""
+	}
+}
+
+// supportOnly allows only support personnel
+func supportOnly(ctx context.Context, params []interface{}) error {
+	if !ctx.Value(cctx.SupportPersonnel).(bool) {
+		return fmt.Errorf("not allowed")
+	}
+	return nil
+}
+
+// supervisorOnly allows only supervising agents
+func supervisorOnly(ctx context.Context, params []interface{}) error {
+	if !ctx.Value(cctx.Supervisor).(bool) {
+		return fmt.Errorf("not allowed")
+	}
+	return nil
+}
+
+// allOf allows calls which pass every given policy
+func allOf(policies ...rpc.Policy) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		for _, policy := range policies {
+			if err = policy(ctx, params); err != nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+// anyOf allows calls which pass at least one of given policies, returning the
+// last rejection otherwise
+func anyOf(policies ...rpc.Policy) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		for _, policy := range policies {
+			if err = policy(ctx, params); err == nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+// roomMember allows only sessions which have joined the room
+func (s *baseService) roomMember(room roomParam) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		sid := ctx.Value(cctx.SessionID).(string)
+
+		var inRoom bool
+		if inRoom, err = s.inRoom(ctx, sid, room(params)); err != nil {
+			return
+		} else if !inRoom {
+			err = fmt.Errorf("not member of given room")
+		}
+		return
+	}
+}
+
+// roomOwner allows only the customer who created the room
+func (s *baseService) roomOwner(room roomParam) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		sid := ctx.Value(cctx.SessionID).(string)
+		_, err = s.findOwnRoom(ctx, sid, room(params))
+		return
+	}
+}
+
+// roomAssignee allows only the agent currently assigned to the room
+func (s *baseService) roomAssignee(room roomParam) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		sid := ctx.Value(cctx.SessionID).(string)
+
+		var intRoomID int
+		if intRoomID, err = strconv.Atoi(room(params)); err != nil {
+			return
+		}
+
+		var assigned bool
+		assigned, err = s.DB.NewSelect().
+			Model((*models.JoinedRoom)(nil)).
+			Where("room_id = ?", intRoomID).
+			Where("user_id = ?", sid).
+			Where("assigned_at IS NOT NULL").
+			Exists(ctx)
+		if err != nil {
+			return
+		} else if !assigned {
+			err = fmt.Errorf("not assigned to given room")
+		}
+		return
+	}
+}
+
+// roomOwnerOrSupport allows the customer who created the room and support personnel
+func (s *baseService) roomOwnerOrSupport(room roomParam) rpc.Policy {
+	return func(ctx context.Context, params []interface{}) (err error) {
+		if ctx.Value(cctx.SupportPersonnel).(bool) {
+			return
+		}
+
+		sid := ctx.Value(cctx.SessionID).(string)
+		if _, err = s.findOwnRoom(ctx, sid, room(params)); err != nil {
+			return
+		}
+		return
+	}
+}
+
+// ChatPolicies declares who may call each method of the chat service
+func ChatPolicies(s *ChatService) rpc.Policies {
+	return rpc.Policies{
+		"send":      s.roomMember(roomArg(0)),
+		"history":   s.roomMember(roomArg(0)),
+		"messages":  s.roomMember(roomArg(0)),
+		"typing":    s.roomMember(roomArg(0)),
+		"presence":  s.roomMember(roomArg(0)),
+		"activity":  s.roomMember(roomArg(0)),
+		"markRead":  s.roomMember(roomArg(0)),
+		"readState": s.roomMember(roomArg(0)),
+		"receipts":  s.roomMember(roomArg(0)),
+		"note":      allOf(supportOnly, s.roomMember(roomArg(0))),
+		"sendMacro": allOf(supportOnly, s.roomMember(roomArg(0))),
+
+		// Sender or supervisor, checked against the message itself
+		"edit":   rpc.AllowAll,
+		"delete": rpc.AllowAll,
+	}
+}
+
+// RoomPolicies declares who may call each method of the room service
+func RoomPolicies(s *RoomService) rpc.Policies {
+	return rpc.Policies{
+		"create":             rpc.AllowAll,
+		"list":               rpc.AllowAll,
+		"join":               supportOnly,
+		"archive":            anyOf(supervisorOnly, s.roomAssignee(roomArg(0)), s.roomOwner(roomArg(0))),
+		"reopen":             s.roomOwnerOrSupport(roomArg(0)),
+		"transfer":           allOf(supportOnly, anyOf(supervisorOnly, s.roomMember(roomArg(0)))),
+		"addTag":             supportOnly,
+		"removeTag":          supportOnly,
+		"setPriority":        supportOnly,
+		"rate":               s.roomOwner(roomArg(0)),
+		"emailTranscript":    s.roomOwner(roomArg(0)),
+		"queue":              supportOnly,
+		"assignments":        supportOnly,
+		"queueStatus":        s.roomOwnerOrSupport(roomArg(0)),
+		"queueStatusUpdates": s.roomOwnerOrSupport(roomArg(0)),
+	}
+}
+
+// AgentPolicies declares who may call each method of the agent service
+func AgentPolicies(s *AgentService) rpc.Policies {
+	return rpc.Policies{
+		"setStatus":   supportOnly,
+		"setCapacity": supportOnly,
+		"status":      supportOnly,
+		"list":        supervisorOnly,
+		"statuses":    supervisorOnly,
+	}
+}
+
+// MacroPolicies declares who may call each method of the macro service
+func MacroPolicies(s *MacroService) rpc.Policies {
+	return rpc.Policies{
+		"list":   supportOnly,
+		"get":    supportOnly,
+		"create": supportOnly,
+		"update": supportOnly,
+		"delete": supportOnly,
+	}
+}
+
+// SearchPolicies declares who may call each method of the search service
+func SearchPolicies(s *SearchService) rpc.Policies {
+	return rpc.Policies{
+		// Results are limited to the session's own rooms unless it is support
+		"messages": rpc.AllowAll,
+	}
+}
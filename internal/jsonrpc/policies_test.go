@@ -0,0 +1,79 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/rpc"
+)
+
+// Registration fails if any exposed method lacks a policy
+func TestPoliciesCoverAllMethods(t *testing.T) {
+	hub := events.NewHub(events.NewMemoryBackend())
+
+	server := rpc.NewServer()
+	server.RequirePolicies()
+
+	chatService := NewChatService(nil, hub, nil)
+	if err := server.RegisterNameWithPolicies("chat", chatService, ChatPolicies(chatService)); err != nil {
+		t.Fatal(err)
+	}
+
+	roomService := NewRoomService(nil, hub, nil, nil, 0, nil)
+	if err := server.RegisterNameWithPolicies("room", roomService, RoomPolicies(roomService)); err != nil {
+		t.Fatal(err)
+	}
+
+	agentService := NewAgentService(nil, hub)
+	if err := server.RegisterNameWithPolicies("agent", agentService, AgentPolicies(agentService)); err != nil {
+		t.Fatal(err)
+	}
+
+	macroService := NewMacroService(nil, hub)
+	if err := server.RegisterNameWithPolicies("macro", macroService, MacroPolicies(macroService)); err != nil {
+		t.Fatal(err)
+	}
+
+	searchService := NewSearchService(nil, hub)
+	if err := server.RegisterNameWithPolicies("search", searchService, SearchPolicies(searchService)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllOfStopsAtFirstRejection(t *testing.T) {
+	rejected := errors.New("rejected")
+	var calls int
+
+	policy := allOf(
+		func(ctx context.Context, params []interface{}) error { calls++; return nil },
+		func(ctx context.Context, params []interface{}) error { calls++; return rejected },
+		func(ctx context.Context, params []interface{}) error { calls++; return nil },
+	)
+
+	if err := policy(context.Background(), nil); err != rejected {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 policies to run, got %d", calls)
+	}
+}
+
+func TestAnyOfStopsAtFirstApproval(t *testing.T) {
+	rejected := errors.New("rejected")
+	var calls int
+
+	policy := anyOf(
+		func(ctx context.Context, params []interface{}) error { calls++; return rejected },
+		func(ctx context.Context, params []interface{}) error { calls++; return nil },
+		func(ctx context.Context, params []interface{}) error { calls++; return rejected },
+	)
+
+	if err := policy(context.Background(), nil); err != nil {
+		t.Fatalf("expected approval, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 policies to run, got %d", calls)
+	}
+}
@@ -0,0 +1,173 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+const (
+	presenceInterval = 30 * time.Second
+	presenceExpiry   = 2 * time.Minute
+)
+
+// Published on events.PresenceTopic
+type presenceChange struct {
+	Activity
+	Connection string `json:"connection"`
+}
+
+// presenceTracker keeps track of connections subscribed to room activity in
+// the room_presence table, shared by all instances. Rows of instances which
+// stopped refreshing their heartbeat expire, so nothing needs to be rebuilt
+// after a restart.
+type presenceTracker struct {
+	db         *bun.DB
+	hub        *events.Hub
+	instanceID string
+}
+
+func newPresenceTracker(db *bun.DB, hub *events.Hub) *presenceTracker {
+	t := &presenceTracker{
+		db:         db,
+		hub:        hub,
+		instanceID: strings.ReplaceAll(uuid.New().String(), "-", ""),
+	}
+
+	go t.run(context.Background())
+
+	return t
+}
+
+// run refreshes the heartbeat of connections of this instance and expires
+// connections of instances which are gone
+func (t *presenceTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := t.db.NewUpdate().
+			Model((*models.RoomPresence)(nil)).
+			Set("heartbeat_at = now()").
+			Where("instance_id = ?", t.instanceID).
+			Exec(ctx)
+		if err != nil {
+			zap.L().Error("failed to refresh room presence", zap.Error(err))
+		}
+
+		if err := t.expire(ctx); err != nil {
+			zap.L().Error("failed to expire room presence", zap.Error(err))
+		}
+	}
+}
+
+// expire removes stale connections and lets subscribers know they went offline
+func (t *presenceTracker) expire(ctx context.Context) (err error) {
+	var expired []models.RoomPresence
+	_, err = t.db.NewDelete().
+		Model((*models.RoomPresence)(nil)).
+		Where("heartbeat_at < now() - make_interval(secs => ?)", presenceExpiry.Seconds()).
+		Returning("*").
+		Exec(ctx, &expired)
+	if err != nil {
+		return
+	}
+
+	for _, presence := range expired {
+		t.publish(ctx, presenceChange{
+			Activity: Activity{
+				Type:        ActivityPresence,
+				RoomID:      strconv.FormatUint(uint64(presence.RoomID), 10),
+				Participant: presence.Participant,
+				UserType:    presence.UserType,
+				Online:      false,
+			},
+			Connection: presence.Connection,
+		})
+	}
+	return
+}
+
+// Participants returns everyone connected to the room, deduplicated by participant
+func (t *presenceTracker) Participants(ctx context.Context, roomID uint) (participants []Participant, err error) {
+	var rows []models.RoomPresence
+	err = t.db.NewSelect().
+		Model(&rows).
+		DistinctOn("participant").
+		Column("participant", "user_type").
+		Where("room_id = ?", roomID).
+		Where("heartbeat_at >= now() - make_interval(secs => ?)", presenceExpiry.Seconds()).
+		OrderExpr("participant, user_type DESC").
+		Scan(ctx)
+	if err != nil {
+		return
+	}
+
+	participants = make([]Participant, 0, len(rows))
+	for _, row := range rows {
+		participants = append(participants, Participant{
+			ID:       row.Participant,
+			UserType: row.UserType,
+		})
+	}
+	return
+}
+
+// Announce records and publishes the presence change of a single connection
+func (t *presenceTracker) Announce(ctx context.Context, connection string, activity Activity) {
+	roomID, err := strconv.ParseUint(activity.RoomID, 10, 64)
+	if err != nil {
+		zap.L().Error("invalid room id for presence change", zap.Error(err))
+		return
+	}
+
+	if activity.Online {
+		_, err = t.db.NewInsert().
+			Model(&models.RoomPresence{
+				Connection:  connection,
+				RoomID:      uint(roomID),
+				Participant: activity.Participant,
+				UserType:    activity.UserType,
+				InstanceID:  t.instanceID,
+			}).
+			On("CONFLICT (connection) DO UPDATE").
+			Set("heartbeat_at = now()").
+			Exec(ctx)
+	} else {
+		_, err = t.db.NewDelete().
+			Model((*models.RoomPresence)(nil)).
+			Where("connection = ?", connection).
+			Exec(ctx)
+	}
+	if err != nil {
+		zap.L().Error("failed to record presence change", zap.Error(err))
+	}
+
+	t.publish(ctx, presenceChange{
+		Activity:   activity,
+		Connection: connection,
+	})
+}
+
+func (t *presenceTracker) publish(ctx context.Context, change presenceChange) {
+	if err := t.hub.Publish(ctx, events.PresenceTopic, events.TypePresence, change); err != nil {
+		zap.L().Error("failed to publish presence change", zap.Error(err))
+	}
+}
+
+// participantID derives a public identifier from the session id, so clients can
+// tell participants apart without learning each other's sessions
+func participantID(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,179 @@
+package jsonrpc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+// movesQueue reports whether the queue event can change positions or the wait estimate.
+// New rooms line up behind everyone else, tags and priorities do not reorder the queue.
+func movesQueue(eventType string) bool {
+	switch eventType {
+	case events.TypeRoomJoined, events.TypeRoomAssigned, events.TypeRoomArchived, events.TypeRoomReopened:
+		return true
+	default:
+		return false
+	}
+}
+
+// queueSnapshot holds the position of every waiting room at one point in time
+type queueSnapshot struct {
+	positions   map[uint]int
+	averageWait sql.NullFloat64
+}
+
+func (q queueSnapshot) status(room models.Room) (status QueueStatus) {
+	status.RoomID = fmt.Sprint(room.ID)
+
+	position, waiting := q.positions[room.ID]
+	if !waiting {
+		return
+	}
+
+	status.Waiting = true
+	status.Position = position
+	status.EstimatedWait = waitEstimate(q.averageWait, position, room.CreatedAt)
+	return
+}
+
+// waitEstimate expects every room ahead to take about as long as recent rooms did
+func waitEstimate(averageWait sql.NullFloat64, position int, createdAt time.Time) *int {
+	if !averageWait.Valid {
+		return nil
+	}
+
+	elapsed := time.Since(createdAt).Seconds()
+	estimate := int(math.Max(0, averageWait.Float64*float64(position)-elapsed))
+	return &estimate
+}
+
+type queueListener struct {
+	room    models.Room
+	updates chan QueueStatus
+}
+
+// queueWatcher recomputes the queue once per change and fans the statuses out
+// to all QueueStatusUpdates subscribers of this instance. It only listens to
+// queue events while there are subscribers.
+type queueWatcher struct {
+	s *RoomService
+
+	mu        sync.Mutex
+	listeners map[*queueListener]struct{}
+	cancel    context.CancelFunc
+}
+
+func newQueueWatcher(s *RoomService) *queueWatcher {
+	return &queueWatcher{
+		s:         s,
+		listeners: make(map[*queueListener]struct{}),
+	}
+}
+
+// Listen registers a listener for status changes of the room until the returned
+// function is called
+func (w *queueWatcher) Listen(room models.Room) (listener *queueListener, stop func()) {
+	listener = &queueListener{
+		room: room,
+		// Only the latest status matters
+		updates: make(chan QueueStatus, 1),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.listeners[listener] = struct{}{}
+	if w.cancel == nil {
+		var ctx context.Context
+		ctx, w.cancel = context.WithCancel(context.Background())
+		go w.run(ctx, w.s.Events.Subscribe(events.QueueTopic))
+	}
+
+	stop = func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.listeners, listener)
+		if len(w.listeners) == 0 && w.cancel != nil {
+			w.cancel()
+			w.cancel = nil
+		}
+	}
+	return
+}
+
+func (w *queueWatcher) run(ctx context.Context, queueEvents *events.Subscription) {
+	defer queueEvents.Close()
+
+	for {
+		select {
+		case evt := <-queueEvents.Events():
+			if !movesQueue(evt.Type) {
+				continue
+			}
+
+			snapshot, err := w.s.queueSnapshot(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					zap.L().Error("failed to compute queue status", zap.Error(err))
+				}
+				continue
+			}
+
+			w.broadcast(snapshot)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *queueWatcher) broadcast(snapshot queueSnapshot) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for listener := range w.listeners {
+		status := snapshot.status(listener.room)
+
+		// Replace a status the subscriber did not pick up yet
+		select {
+		case <-listener.updates:
+		default:
+		}
+		listener.updates <- status
+	}
+}
+
+// queueSnapshot computes the positions of all waiting rooms at once
+func (s *RoomService) queueSnapshot(ctx context.Context) (snapshot queueSnapshot, err error) {
+	var rows []struct {
+		ID       uint `bun:"id"`
+		Position int  `bun:"position"`
+	}
+	err = s.DB.NewSelect().
+		TableExpr("rooms AS r").
+		ColumnExpr("r.id").
+		ColumnExpr("ROW_NUMBER() OVER (ORDER BY r.created_at, r.id) AS position").
+		Where("r.archived_at IS NULL").
+		Where(unjoinedRoom).
+		Scan(ctx, &rows)
+	if err != nil {
+		return
+	}
+
+	snapshot.positions = make(map[uint]int, len(rows))
+	for _, row := range rows {
+		snapshot.positions[row.ID] = row.Position
+	}
+
+	snapshot.averageWait, err = s.averageWait(ctx)
+	return
+}
@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+func TestQueueStatusEqual(t *testing.T) {
+	first, second := 60, 60
+	a := QueueStatus{RoomID: "1", Waiting: true, Position: 2, EstimatedWait: &first}
+	b := QueueStatus{RoomID: "1", Waiting: true, Position: 2, EstimatedWait: &second}
+
+	if !a.Equal(b) {
+		t.Fatal("expected statuses with equal estimates to be equal")
+	}
+
+	b.EstimatedWait = nil
+	if a.Equal(b) {
+		t.Fatal("expected missing estimate to differ")
+	}
+}
+
+func TestQueueStatus(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	s := NewRoomService(db, events.NewHub(events.NewMemoryBackend()), nil, nil, 0, nil)
+	now := time.Now()
+
+	rooms := []models.Room{
+		// Picked up by an agent after two minutes
+		{Owner: "customer1", CreatedAt: now.Add(-time.Hour)},
+		{Owner: "customer2", CreatedAt: now.Add(-time.Minute)},
+		{Owner: "customer3", CreatedAt: now},
+	}
+	if _, err := db.NewInsert().Model(&rooms).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	joinedAt := rooms[0].CreatedAt.Add(2 * time.Minute)
+	joined := []models.JoinedRoom{
+		{UserID: "customer1", RoomID: rooms[0].ID},
+		{UserID: "agent", RoomID: rooms[0].ID, UserType: models.UserTypeSupport, JoinedAt: &joinedAt},
+		{UserID: "customer2", RoomID: rooms[1].ID},
+		{UserID: "customer3", RoomID: rooms[2].ID},
+	}
+	if _, err := db.NewInsert().Model(&joined).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := s.queueStatus(ctx, rooms[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !status.Waiting || status.Position != 2 {
+		t.Fatalf("expected second waiting room, got %+v", status)
+	}
+	if status.EstimatedWait == nil || *status.EstimatedWait < 200 || *status.EstimatedWait > 240 {
+		t.Fatalf("expected an estimate of about four minutes, got %+v", status.EstimatedWait)
+	}
+
+	if status, err = s.queueStatus(ctx, rooms[0]); err != nil {
+		t.Fatal(err)
+	} else if status.Waiting {
+		t.Fatalf("expected joined room not to wait, got %+v", status)
+	}
+}
+
+func TestQueueSnapshotStatus(t *testing.T) {
+	snapshot := queueSnapshot{
+		positions:   map[uint]int{1: 1, 3: 2},
+		averageWait: sql.NullFloat64{Float64: 120, Valid: true},
+	}
+
+	status := snapshot.status(models.Room{ID: 3, CreatedAt: time.Now()})
+	if !status.Waiting || status.Position != 2 {
+		t.Fatalf("expected second waiting room, got %+v", status)
+	}
+	if status.EstimatedWait == nil || *status.EstimatedWait < 235 || *status.EstimatedWait > 240 {
+		t.Fatalf("expected an estimate of four minutes, got %+v", status.EstimatedWait)
+	}
+
+	if status = snapshot.status(models.Room{ID: 2}); status.Waiting || status.Position != 0 {
+		t.Fatalf("expected room missing from the queue not to wait, got %+v", status)
+	}
+}
+
+func TestQueueWatcherKeepsLatestStatus(t *testing.T) {
+	s := NewRoomService(nil, events.NewHub(events.NewMemoryBackend()), nil, nil, 0, nil)
+
+	listener, stop := s.queue.Listen(models.Room{ID: 1})
+	defer stop()
+
+	s.queue.broadcast(queueSnapshot{positions: map[uint]int{1: 2}})
+	s.queue.broadcast(queueSnapshot{positions: map[uint]int{1: 1}})
+
+	if status := <-listener.updates; status.Position != 1 {
+		t.Fatalf("expected latest position, got %+v", status)
+	}
+}
@@ -1,8 +1,76 @@
 package jsonrpc
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+// Sent by client, all fields are optional
+type CreateRoomInput struct {
+	CustomerName  string `json:"customerName"`
+	CustomerEmail string `json:"customerEmail"`
+}
+
+// Sent by client, all fields are optional
+type RoomListFilter struct {
+	Tags []string `json:"tags"`
+}
 
 type Room struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID           string     `json:"id"`
+	CustomerName string     `json:"customerName,omitempty"`
+	Priority     string     `json:"priority"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ArchivedAt   *time.Time `json:"archivedAt,omitempty"`
+	Unread       int        `json:"unread"`
+	Tags         []string   `json:"tags,omitempty"`
+
+	FirstResponseDueAt *time.Time `json:"firstResponseDueAt,omitempty"`
+	NextResponseDueAt  *time.Time `json:"nextResponseDueAt,omitempty"`
+}
+
+func RoomFromModel(room models.Room) (r Room) {
+	r.FromModel(room)
+	return
+}
+
+func (r *Room) FromModel(room models.Room) {
+	r.ID = fmt.Sprint(room.ID)
+	r.CreatedAt = room.CreatedAt
+	r.ArchivedAt = room.ArchivedAt
+	r.Priority = room.Priority
+	r.FirstResponseDueAt = room.FirstResponseDueAt
+	r.NextResponseDueAt = room.NextResponseDueAt
+	if room.CustomerName != nil {
+		r.CustomerName = *room.CustomerName
+	}
+}
+
+// Pushed to support queue subscribers
+type RoomEvent struct {
+	Type string `json:"type"`
+	Room Room   `json:"room"`
+}
+
+// Where a customer's room stands in the queue. Position and EstimatedWait are
+// only set while the room is waiting for an agent, EstimatedWait is in seconds
+// and omitted when there is no recent data to base it on.
+type QueueStatus struct {
+	RoomID        string `json:"roomId"`
+	Waiting       bool   `json:"waiting"`
+	Position      int    `json:"position,omitempty"`
+	EstimatedWait *int   `json:"estimatedWait,omitempty"`
+}
+
+// Equal compares the statuses by value, including the wait estimate
+func (q QueueStatus) Equal(other QueueStatus) bool {
+	if q.RoomID != other.RoomID || q.Waiting != other.Waiting || q.Position != other.Position {
+		return false
+	}
+	if q.EstimatedWait == nil || other.EstimatedWait == nil {
+		return q.EstimatedWait == other.EstimatedWait
+	}
+	return *q.EstimatedWait == *other.EstimatedWait
 }
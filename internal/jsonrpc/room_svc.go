@@ -3,35 +3,81 @@ package jsonrpc
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/uptrace/bun"
+	"go.uber.org/zap"
 
+	"github.com/helpify-project/backend/internal/agents"
 	"github.com/helpify-project/backend/internal/cctx"
 	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+	"github.com/helpify-project/backend/internal/mailer"
+	"github.com/helpify-project/backend/internal/routing"
+	"github.com/helpify-project/backend/internal/rpc"
+	"github.com/helpify-project/backend/internal/sla"
+	"github.com/helpify-project/backend/internal/transcript"
 )
 
-func NewRoomService(db *bun.DB) *RoomService {
-	return &RoomService{
+func NewRoomService(db *bun.DB, hub *events.Hub, router *routing.Router, timers *sla.Timers, reopenWindow time.Duration, mail *mailer.Queue) *RoomService {
+	s := &RoomService{
 		baseService: baseService{
-			DB: db,
+			DB:     db,
+			Events: hub,
 		},
+		router:       router,
+		timers:       timers,
+		reopenWindow: reopenWindow,
+		mail:         mail,
 	}
+	s.queue = newQueueWatcher(s)
+	return s
 }
 
 type RoomService struct {
 	baseService
+
+	router *routing.Router
+	timers *sla.Timers
+	// How long after archiving a room may be reopened
+	reopenWindow time.Duration
+	// Nil if email is not configured
+	mail  *mailer.Queue
+	queue *queueWatcher
 }
 
-func (s *RoomService) Create(ctx context.Context) (roomID string, err error) {
+func (s *RoomService) Create(ctx context.Context, input *CreateRoomInput) (roomID string, err error) {
 	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
 
 	newRoom := models.Room{
 		Owner:     sid,
 		CreatedAt: time.Now(),
 	}
 
+	if input != nil {
+		if name := strings.TrimSpace(input.CustomerName); name != "" {
+			newRoom.CustomerName = &name
+		}
+
+		if email := strings.TrimSpace(input.CustomerEmail); email != "" {
+			var address *mail.Address
+			if address, err = mail.ParseAddress(email); err != nil {
+				err = fmt.Errorf("invalid email address")
+				return
+			}
+			newRoom.CustomerEmail = &address.Address
+		}
+	}
+	s.timers.Start(&newRoom)
+
+	var assigned *routing.Candidate
 	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
 		// Check if user has any active rooms before creating new one
 		/*
@@ -59,14 +105,20 @@ func (s *RoomService) Create(ctx context.Context) (roomID string, err error) {
 
 		// Join to the room as well
 		newJoinedRoom := models.JoinedRoom{
-			UserID: sid,
-			RoomID: newRoom.ID,
+			UserID:   sid,
+			RoomID:   newRoom.ID,
+			UserType: sessionUserType(supportPersonnel),
 		}
 
 		_, err = tx.NewInsert().
 			Model(&newJoinedRoom).
 			Exec(ctx)
+		if err != nil {
+			return
+		}
 
+		// Hand the room over to an agent, if routing is enabled
+		assigned, err = s.router.Assign(ctx, tx, newRoom)
 		return
 	})
 	if err != nil {
@@ -74,17 +126,16 @@ func (s *RoomService) Create(ctx context.Context) (roomID string, err error) {
 	}
 
 	roomID = fmt.Sprint(newRoom.ID)
+	s.publishQueueEvent(ctx, events.TypeRoomCreated, newRoom)
+	if assigned != nil {
+		s.publishAssignment(ctx, assigned.SessionID, newRoom)
+	}
 	return
 }
 
 func (s *RoomService) Join(ctx context.Context, roomID string) (ok bool, err error) {
 	sid := ctx.Value(cctx.SessionID).(string)
-	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
-
-	if !supportPersonnel {
-		err = fmt.Errorf("not allowed")
-		return
-	}
+	supervisor := ctx.Value(cctx.Supervisor).(bool)
 
 	// Find the room
 	var room models.Room
@@ -92,59 +143,679 @@ func (s *RoomService) Join(ctx context.Context, roomID string) (ok bool, err err
 		return
 	}
 
-	newJoinedRoom := models.JoinedRoom{
-		UserID: sid,
-		RoomID: room.ID,
-	}
+	var assigned bool
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		// Find out who is currently responsible for the room
+		var assigneeSID, assigneeStatus string
+		err = tx.NewSelect().
+			TableExpr("joined_rooms AS jr").
+			Join("LEFT JOIN agents AS a ON a.session_id = jr.user_id").
+			ColumnExpr("jr.user_id").
+			ColumnExpr("COALESCE(a.status, ?)", models.AgentOffline).
+			Where("jr.room_id = ?", room.ID).
+			Where("jr.assigned_at IS NOT NULL").
+			For("UPDATE OF jr").
+			Scan(ctx, &assigneeSID, &assigneeStatus)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		} else if err != nil {
+			return
+		}
+		hasAssignee := assigneeSID != ""
 
-	_, err = s.DB.NewInsert().
-		Model(&newJoinedRoom).
-		Exec(ctx)
+		if assigneeSID == sid {
+			err = fmt.Errorf("already joined")
+			return
+		}
+
+		if hasAssignee && assigneeStatus != models.AgentOnline {
+			// Rooms of agents who went away may be taken over
+			_, err = tx.NewUpdate().
+				Model((*models.JoinedRoom)(nil)).
+				Set("assigned_at = NULL").
+				Where("room_id = ?", room.ID).
+				Where("user_id = ?", assigneeSID).
+				Exec(ctx)
+			if err != nil {
+				return
+			}
+			hasAssignee = false
+		} else if hasAssignee && !supervisor {
+			// Supervisors may look into assigned rooms without taking them over
+			err = fmt.Errorf("room is assigned to another agent")
+			return
+		}
+
+		newJoinedRoom := models.JoinedRoom{
+			UserID:   sid,
+			RoomID:   room.ID,
+			UserType: models.UserTypeSupport,
+		}
+		if !hasAssignee {
+			now := time.Now()
+			newJoinedRoom.AssignedAt = &now
+			assigned = true
+		}
+
+		_, err = tx.NewInsert().
+			Model(&newJoinedRoom).
+			Exec(ctx)
+		return
+	})
 
 	ok = err == nil
+	if ok {
+		s.publishQueueEvent(ctx, events.TypeRoomJoined, room)
+		if assigned {
+			s.publishAssignment(ctx, sid, room)
+		}
+	}
 	return
 }
 
-func (s *RoomService) Archive(ctx context.Context, roomID string) (ok bool, err error) {
-	//sid := ctx.Value(cctx.SessionID).(string)
-	//supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+// Transfer hands the room over to another agent. The note is kept as an internal
+// message for the receiving agent, while the customer is told who took over.
+// Only the assigned agent or a supervisor may transfer an assigned room.
+func (s *RoomService) Transfer(ctx context.Context, roomID string, targetAgent string, note string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	supervisor := ctx.Value(cctx.Supervisor).(bool)
+	now := time.Now()
 
-	// Find the room
 	var room models.Room
-	//var inRoom bool
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	if room.ArchivedAt != nil {
+		err = errRoomArchived
+		return
+	}
+
+	var targetID uint64
+	if targetID, err = strconv.ParseUint(targetAgent, 10, 64); err != nil {
+		return
+	}
 
+	var target models.Agent
+	var messages []models.Message
 	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
-		if room, err = s.findRoom(ctx, roomID); err != nil {
+		err = tx.NewSelect().
+			Model(&target).
+			Where("id = ?", targetID).
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("agent not found")
+			return
+		} else if err != nil {
 			return
 		}
 
-		/*
-			if inRoom, err = s.inRoom(ctx, sid, roomID); err != nil {
+		if target.Status == models.AgentOffline {
+			err = fmt.Errorf("agent is offline")
+			return
+		}
+
+		var assignee models.JoinedRoom
+		err = tx.NewSelect().
+			Model(&assignee).
+			Where("room_id = ?", room.ID).
+			Where("assigned_at IS NOT NULL").
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		} else if err != nil {
+			return
+		}
+
+		if assignee.UserID == target.SessionID {
+			err = fmt.Errorf("room is already assigned to given agent")
+			return
+		} else if assignee.UserID != "" && assignee.UserID != sid && !supervisor {
+			err = fmt.Errorf("room is assigned to another agent")
+			return
+		}
+
+		// Previous agent stays in the room, but is no longer responsible for it
+		if assignee.UserID != "" {
+			_, err = tx.NewUpdate().
+				Model(&assignee).
+				Set("assigned_at = NULL").
+				WherePK().
+				Exec(ctx)
+			if err != nil {
 				return
 			}
+		}
 
-			if !inRoom {
-				err = fmt.Errorf("not member of given room")
-				return
+		newJoinedRoom := models.JoinedRoom{
+			UserID:     target.SessionID,
+			RoomID:     room.ID,
+			UserType:   models.UserTypeSupport,
+			AssignedAt: &now,
+		}
+
+		_, err = tx.NewInsert().
+			Model(&newJoinedRoom).
+			On("CONFLICT (user_id, room_id) DO UPDATE").
+			Set("assigned_at = EXCLUDED.assigned_at").
+			Exec(ctx)
+		if err != nil {
+			return
+		}
+
+		if note != "" {
+			messages = append(messages, models.Message{
+				RoomID:    room.ID,
+				Sender:    sid,
+				Timestamp: now,
+				UserType:  models.UserTypeInternal,
+				Message:   note,
+			})
+		}
+
+		messages = append(messages, models.Message{
+			RoomID:    room.ID,
+			Timestamp: now,
+			UserType:  models.UserTypeSystem,
+			Message:   fmt.Sprintf("You are now chatting with %s", target.DisplayName),
+		})
+
+		_, err = tx.NewInsert().
+			Model(&messages).
+			Exec(ctx)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	ok = true
+	for _, msg := range messages {
+		s.publishMessage(ctx, msg)
+	}
+	s.publishAssignment(ctx, target.SessionID, room)
+	return
+}
+
+// Reopen brings an archived room back within the reopen window. The previously
+// assigned agent keeps the room if they are available, otherwise it is routed anew.
+func (s *RoomService) Reopen(ctx context.Context, roomID string) (ok bool, err error) {
+	now := time.Now()
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	if room.ArchivedAt == nil {
+		err = fmt.Errorf("room is not archived")
+		return
+	} else if now.Sub(*room.ArchivedAt) > s.reopenWindow {
+		err = fmt.Errorf("room can no longer be reopened")
+		return
+	}
+
+	var assignee string
+	systemMsg := models.Message{
+		RoomID:    room.ID,
+		Timestamp: now,
+		UserType:  models.UserTypeSystem,
+		Message:   "Conversation reopened",
+	}
+
+	// Support is expected to respond to the reopened conversation again
+	s.timers.Reopen(&room, now)
+
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		var res sql.Result
+		res, err = tx.NewUpdate().
+			Model(&room).
+			Set("archived_at = NULL").
+			Set("first_response_warn_at = ?", room.FirstResponseWarnAt).
+			Set("first_response_due_at = ?", room.FirstResponseDueAt).
+			Set("next_response_warn_at = ?", room.NextResponseWarnAt).
+			Set("next_response_due_at = ?", room.NextResponseDueAt).
+			WherePK().
+			Where("archived_at IS NOT NULL").
+			Exec(ctx)
+		if err != nil {
+			return
+		} else if affected, _ := res.RowsAffected(); affected == 0 {
+			err = fmt.Errorf("room is not archived")
+			return
+		}
+		room.ArchivedAt = nil
+
+		// Find the previous agent and whether they can take the room back
+		var previous []agents.Entry
+		err = agents.Select(tx, &previous).
+			Join("JOIN joined_rooms AS jr ON jr.user_id = a.session_id").
+			Where("jr.room_id = ?", room.ID).
+			Where("jr.assigned_at IS NOT NULL").
+			Scan(ctx)
+		if err != nil {
+			return
+		}
+
+		if len(previous) > 0 {
+			agent := previous[0]
+			update := tx.NewUpdate().
+				Model((*models.JoinedRoom)(nil)).
+				Where("room_id = ?", room.ID).
+				Where("user_id = ?", agent.SessionID)
+
+			// The room already counts towards the open rooms of the agent
+			if agent.Status == models.AgentOnline && agent.OpenRooms <= agent.MaxRooms {
+				assignee = agent.SessionID
+				update = update.Set("assigned_at = ?", now)
+			} else {
+				update = update.Set("assigned_at = NULL")
 			}
 
-			if !supportPersonnel && room.Owner != sid {
-				err = fmt.Errorf("can only interact with your own rooms")
+			if _, err = update.Exec(ctx); err != nil {
 				return
 			}
+		}
 
-			if room.ArchivedAt != nil {
-				err = fmt.Errorf("already archived")
+		if assignee == "" {
+			var assigned *routing.Candidate
+			if assigned, err = s.router.Assign(ctx, tx, room); err != nil {
 				return
+			} else if assigned != nil {
+				assignee = assigned.SessionID
 			}
-		*/
+		}
+
+		_, err = tx.NewInsert().
+			Model(&systemMsg).
+			Exec(ctx)
+		return
+	})
+
+	ok = err == nil
+	if ok {
+		s.publishMessage(ctx, systemMsg)
+		s.publishQueueEvent(ctx, events.TypeRoomReopened, room)
+		if assignee != "" {
+			s.publishAssignment(ctx, assignee, room)
+		}
+	}
+	return
+}
+
+// SetPriority changes the priority of the room, moving its pending SLA deadlines
+func (s *RoomService) SetPriority(ctx context.Context, roomID string, priority string) (ok bool, err error) {
+	if err = sla.ValidatePriority(priority); err != nil {
+		return
+	}
+
+	var room models.Room
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		if room, err = s.findRoom(ctx, roomID); err != nil {
+			return
+		}
+
+		err = tx.NewSelect().
+			Model(&room).
+			WherePK().
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			return
+		}
+
+		s.timers.SetPriority(&room, priority)
 
 		_, err = tx.NewUpdate().
 			Model(&room).
-			Where("id = ?", room.ID).
-			Set("archived_at = ?", time.Now()).
+			Column("priority", "first_response_warn_at", "first_response_due_at", "next_response_warn_at", "next_response_due_at").
+			WherePK().
+			Exec(ctx)
+		return
+	})
+
+	ok = err == nil
+	if ok {
+		s.publishQueueEvent(ctx, events.TypeRoomPrioritized, room)
+	}
+	return
+}
+
+func (s *RoomService) Archive(ctx context.Context, roomID string) (ok bool, err error) {
+	// Find the room
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	// Only one of concurrent archive calls gets to update the row
+	now := time.Now()
+	var res sql.Result
+	res, err = s.DB.NewUpdate().
+		Model(&room).
+		Set("archived_at = ?", now).
+		Where("id = ?", room.ID).
+		Where("archived_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return
+	}
+
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return
+	} else if affected == 0 {
+		err = fmt.Errorf("already archived")
+		return
+	}
+	room.ArchivedAt = &now
+
+	ok = true
+	s.publishQueueEvent(ctx, events.TypeRoomArchived, room)
+	return
+}
+
+// AddTag labels the room with given tag, creating the tag on first use
+func (s *RoomService) AddTag(ctx context.Context, roomID string, tag string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	now := time.Now()
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	name := normalizeTag(tag)
+	if !tagPattern.MatchString(name) {
+		err = fmt.Errorf("tags may only contain up to 32 letters, digits, dashes and underscores")
+		return
+	}
+
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		dbTag := models.Tag{
+			Name:      name,
+			CreatedAt: now,
+		}
+
+		// Updating on conflict makes the existing id come back
+		_, err = tx.NewInsert().
+			Model(&dbTag).
+			On("CONFLICT (name) DO UPDATE").
+			Set("name = EXCLUDED.name").
+			Returning("id").
+			Exec(ctx)
+		if err != nil {
+			return
+		}
+
+		roomTag := models.RoomTag{
+			RoomID:  room.ID,
+			TagID:   dbTag.ID,
+			AddedBy: sid,
+			AddedAt: now,
+		}
+
+		_, err = tx.NewInsert().
+			Model(&roomTag).
+			On("CONFLICT DO NOTHING").
+			Exec(ctx)
+		return
+	})
+
+	ok = err == nil
+	if ok {
+		s.publishTags(ctx, room)
+	}
+	return
+}
+
+// RemoveTag removes the tag from the room
+func (s *RoomService) RemoveTag(ctx context.Context, roomID string, tag string) (ok bool, err error) {
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	_, err = s.DB.NewDelete().
+		Model((*models.RoomTag)(nil)).
+		Where("room_id = ?", room.ID).
+		Where("tag_id = (SELECT id FROM tags WHERE name = ?)", normalizeTag(tag)).
+		Exec(ctx)
+
+	ok = err == nil
+	if ok {
+		s.publishTags(ctx, room)
+	}
+	return
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// Transcript emails still unsent after this long are assumed to be lost with the
+// in-memory mail queue
+const (
+	// A room whose transcript email failed or got lost on restart may be retried after this long
+	transcriptEmailCooldown = time.Hour
+	// Transcript emails a session may request per day, across all of its rooms
+	transcriptEmailsPerDay = 3
+)
+
+// EmailTranscript sends the transcript of an archived room to the address given
+// when the room was opened, once per room
+func (s *RoomService) EmailTranscript(ctx context.Context, roomID string, email string) (ok bool, err error) {
+	sid := ctx.Value(cctx.SessionID).(string)
+	now := time.Now()
+
+	if s.mail == nil {
+		err = fmt.Errorf("email is not available")
+		return
+	}
+
+	var address *mail.Address
+	if address, err = mail.ParseAddress(email); err != nil {
+		err = fmt.Errorf("invalid email address")
+		return
+	}
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	if room.ArchivedAt == nil {
+		err = fmt.Errorf("transcripts can only be emailed once the room is archived")
+		return
+	}
+
+	// Never relay to arbitrary addresses
+	if room.CustomerEmail == nil || !strings.EqualFold(*room.CustomerEmail, address.Address) {
+		err = fmt.Errorf("transcripts can only be emailed to the address given when opening the room")
+		return
+	}
+
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		// Serializes requests of the session, so the daily limit holds
+		_, err = tx.NewSelect().
+			Model((*models.Room)(nil)).
+			Column("id").
+			Where("owner = ?", sid).
+			For("UPDATE").
+			Exec(ctx)
+		if err != nil {
+			return
+		}
+
+		var requested int
+		requested, err = tx.NewSelect().
+			Model((*models.Room)(nil)).
+			Where("owner = ?", sid).
+			Where("transcript_email_requested_at > ? OR transcript_emailed_at > ?", now.Add(-24*time.Hour), now.Add(-24*time.Hour)).
+			Count(ctx)
+		if err != nil {
+			return
+		} else if requested >= transcriptEmailsPerDay {
+			err = fmt.Errorf("too many transcript emails, try again later")
+			return
+		}
+
+		// Failed and lost emails keep their request time, so they are only retried after the cooldown
+		var res sql.Result
+		res, err = tx.NewUpdate().
+			Model(&room).
+			Set("transcript_email_requested_at = ?", now).
+			WherePK().
+			Where("transcript_emailed_at IS NULL").
+			Where("transcript_email_requested_at IS NULL OR transcript_email_requested_at < ?", now.Add(-transcriptEmailCooldown)).
 			Exec(ctx)
+		if err != nil {
+			return
+		} else if affected, _ := res.RowsAffected(); affected == 0 {
+			err = fmt.Errorf("transcript has already been emailed")
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	if err = s.enqueueTranscript(ctx, room, *room.CustomerEmail); err != nil {
+		// Nothing was sent, let the customer try again
+		s.resetTranscriptEmail(room)
+		return
+	}
+
+	ok = true
+	return
+}
 
+func (s *RoomService) enqueueTranscript(ctx context.Context, room models.Room, to string) (err error) {
+	var lines []transcript.Line
+	if lines, err = transcript.Collect(ctx, s.DB, room.ID, false); err != nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"RoomID":       room.ID,
+		"CustomerName": "",
+		"Lines":        lines,
+	}
+	if room.CustomerName != nil {
+		data["CustomerName"] = *room.CustomerName
+	}
+
+	msg := mailer.Message{
+		To:      to,
+		Subject: fmt.Sprintf("Transcript of your conversation #%d", room.ID),
+	}
+	if msg.Text, msg.HTML, err = mailer.Render("transcript", data); err != nil {
+		return
+	}
+
+	return s.mail.Enqueue(msg, func(err error) {
+		if err != nil {
+			// The request time stays, so the customer may try again after the cooldown
+			zap.L().Warn("failed to email transcript", zap.Uint("room", room.ID), zap.Error(err))
+			return
+		}
+		s.transcriptEmailed(room)
+	})
+}
+
+// transcriptEmailed marks the transcript as sent
+func (s *RoomService) transcriptEmailed(room models.Room) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.DB.NewUpdate().
+		Model(&room).
+		Set("transcript_emailed_at = ?", time.Now()).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		zap.L().Error("failed to update transcript email state", zap.Uint("room", room.ID), zap.Error(err))
+	}
+}
+
+// resetTranscriptEmail withdraws a request which never made it into the mail queue
+func (s *RoomService) resetTranscriptEmail(room models.Room) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.DB.NewUpdate().
+		Model(&room).
+		Set("transcript_email_requested_at = NULL").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		zap.L().Error("failed to update transcript email state", zap.Uint("room", room.ID), zap.Error(err))
+	}
+}
+
+// Rate stores the satisfaction rating of an archived room. Each room can be rated once.
+func (s *RoomService) Rate(ctx context.Context, roomID string, score int, comment *string) (ok bool, err error) {
+	if score < 1 || score > 5 {
+		err = fmt.Errorf("score must be between 1 and 5")
+		return
+	}
+
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	if room.ArchivedAt == nil {
+		err = fmt.Errorf("room can only be rated once archived")
+		return
+	}
+
+	rating := models.RoomRating{
+		RoomID:    room.ID,
+		Score:     score,
+		CreatedAt: time.Now(),
+	}
+	if comment != nil {
+		if text := strings.TrimSpace(*comment); text != "" {
+			rating.Comment = &text
+		}
+	}
+
+	err = s.DB.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) (err error) {
+		var rated bool
+		rated, err = tx.NewSelect().
+			Model((*models.RoomRating)(nil)).
+			Where("room_id = ?", room.ID).
+			Exists(ctx)
+		if err != nil {
+			return
+		} else if rated {
+			err = fmt.Errorf("room has already been rated")
+			return
+		}
+
+		// Credit the agent who was responsible for the room
+		var agentIDs []uint
+		err = tx.NewSelect().
+			TableExpr("joined_rooms AS jr").
+			Join("JOIN agents AS a ON a.session_id = jr.user_id").
+			ColumnExpr("a.id").
+			Where("jr.room_id = ?", room.ID).
+			Where("jr.assigned_at IS NOT NULL").
+			Scan(ctx, &agentIDs)
+		if err != nil {
+			return
+		}
+		if len(agentIDs) > 0 {
+			rating.AgentID = &agentIDs[0]
+		}
+
+		_, err = tx.NewInsert().
+			Model(&rating).
+			Exec(ctx)
 		return
 	})
 
@@ -152,19 +823,81 @@ func (s *RoomService) Archive(ctx context.Context, roomID string) (ok bool, err
 	return
 }
 
-func (s *RoomService) List(ctx context.Context) (rooms []Room, err error) {
+type roomListEntry struct {
+	models.Room `bun:",extend"`
+
+	Unread int      `bun:",scanonly"`
+	Tags   []string `bun:",array,scanonly"`
+}
+
+// List returns open rooms of the session, or all open rooms for support personnel.
+// Support personnel may narrow the list down to rooms carrying all given tags.
+func (s *RoomService) List(ctx context.Context, filter *RoomListFilter) (rooms []Room, err error) {
 	rooms = make([]Room, 0)
 	sid := ctx.Value(cctx.SessionID).(string)
 	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
 
-	var dbRooms []models.Room
+	var dbRooms []roomListEntry
 
+	// Messages from others past the session's read marker
 	query := s.DB.NewSelect().
 		Model(&dbRooms).
-		Column("id", "owner", "created_at", "archived_at")
+		Column("id", "owner", "customer_name", "priority", "created_at", "archived_at").
+		Column("first_response_due_at", "next_response_due_at").
+		ColumnExpr(`(
+			SELECT COUNT(*) FROM messages AS m
+			WHERE m.room_id = ?TableAlias.id
+			AND m.sender != ?
+			AND (? OR m.user_type != ?)
+			AND m.id > COALESCE((
+				SELECT jr.last_read_message_id FROM joined_rooms AS jr
+				WHERE jr.room_id = ?TableAlias.id AND jr.user_id = ?
+			), 0)
+		) AS unread`, sid, supportPersonnel, models.UserTypeInternal, sid)
 
 	if !supportPersonnel {
 		query = query.Where("owner = ?", sid)
+	} else {
+		query = query.ColumnExpr(`ARRAY(
+			SELECT t.name FROM room_tags AS rt
+			JOIN tags AS t ON t.id = rt.tag_id
+			WHERE rt.room_id = ?TableAlias.id
+			ORDER BY t.name
+		) AS tags`)
+
+		if filter != nil {
+			for _, tag := range filter.Tags {
+				query = query.Where(`EXISTS (
+					SELECT 1 FROM room_tags AS rt
+					JOIN tags AS t ON t.id = rt.tag_id
+					WHERE rt.room_id = ?TableAlias.id AND t.name = ?
+				)`, normalizeTag(tag))
+			}
+		}
+
+		// Own rooms first, then rooms without an online agent if the agent
+		// could take on another one
+		query = query.OrderExpr(`EXISTS (
+			SELECT 1 FROM joined_rooms AS jr
+			WHERE jr.room_id = ?TableAlias.id
+			AND jr.user_id = ?
+			AND jr.assigned_at IS NOT NULL
+		) DESC`, sid)
+
+		var agent agents.Entry
+		if err = agents.Select(s.DB, &agent).Where("a.session_id = ?", sid).Scan(ctx); err != nil {
+			return
+		}
+
+		if agent.Status == models.AgentOnline && agent.OpenRooms < agent.MaxRooms {
+			query = query.OrderExpr(`NOT EXISTS (
+				SELECT 1 FROM joined_rooms AS jr
+				JOIN agents AS a ON a.session_id = jr.user_id
+				WHERE jr.room_id = ?TableAlias.id
+				AND jr.assigned_at IS NOT NULL
+				AND a.status = ?
+			) DESC`, models.AgentOnline)
+		}
 	}
 
 	err = query.
@@ -176,33 +909,231 @@ func (s *RoomService) List(ctx context.Context) (rooms []Room, err error) {
 		return
 	}
 
-	for _, room := range dbRooms {
-		rooms = append(rooms, Room{
-			ID:        fmt.Sprint(room.ID),
-			CreatedAt: room.CreatedAt,
-		})
+	for _, entry := range dbRooms {
+		room := RoomFromModel(entry.Room)
+		room.Unread = entry.Unread
+		room.Tags = entry.Tags
+		rooms = append(rooms, room)
 	}
 
 	return
 }
 
-/*
-func (s *RoomService) NewRooms(ctx context.Context) (sub *rpc.Subscription, err error) {
-	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+// Queue pushes created, joined and archived rooms to support personnel
+func (s *RoomService) Queue(ctx context.Context) (sub *rpc.Subscription, err error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		err = rpc.ErrNotificationsUnsupported
+		return
+	}
 
-	if !supportPersonnel {
-		err = fmt.Errorf("not allowed")
+	sub = notifier.CreateSubscription()
+	queueEvents := s.Events.Subscribe(events.QueueTopic)
+
+	go forwardEvents(notifier, sub, queueEvents, func(evt events.Event) (interface{}, bool) {
+		return evt.Data, true
+	})
+
+	return
+}
+
+// Assignments pushes rooms routed to the calling agent
+func (s *RoomService) Assignments(ctx context.Context) (sub *rpc.Subscription, err error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		err = rpc.ErrNotificationsUnsupported
 		return
 	}
 
+	sid := ctx.Value(cctx.SessionID).(string)
+
+	sub = notifier.CreateSubscription()
+	agentEvents := s.Events.Subscribe(events.AgentTopic(sid))
+
+	go forwardEvents(notifier, sub, agentEvents, func(evt events.Event) (interface{}, bool) {
+		return evt.Data, evt.Type == events.TypeRoomAssigned
+	})
+
+	return
+}
+
+// QueueStatus returns the position of the room among rooms waiting for an agent
+func (s *RoomService) QueueStatus(ctx context.Context, roomID string) (status QueueStatus, err error) {
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	status, err = s.queueStatus(ctx, room)
+	return
+}
+
+// QueueStatusUpdates pushes the queue status of the room whenever the queue changes
+func (s *RoomService) QueueStatusUpdates(ctx context.Context, roomID string) (sub *rpc.Subscription, err error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		err = rpc.ErrNotificationsUnsupported
 		return
 	}
 
+	var room models.Room
+	if room, err = s.findRoom(ctx, roomID); err != nil {
+		return
+	}
+
+	var status QueueStatus
+	if status, err = s.queueStatus(ctx, room); err != nil {
+		return
+	}
+
 	sub = notifier.CreateSubscription()
+	listener, stop := s.queue.Listen(room)
+
+	// Buffered until the subscription id has reached the client
+	if err = notifier.Notify(sub.ID, status); err != nil {
+		stop()
+		return
+	}
+
+	go func() {
+		defer stop()
+
+		for {
+			select {
+			case updated := <-listener.updates:
+				if updated.Equal(status) {
+					continue
+				}
+
+				status = updated
+				if err := notifier.Notify(sub.ID, status); err != nil {
+					zap.L().Debug("failed to notify subscriber", zap.Error(err))
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+const (
+	// Rooms picked up within this period make up the wait estimate
+	waitEstimateWindow  = 24 * time.Hour
+	waitEstimateSamples = 20
+)
+
+// unjoinedRoom matches rooms nobody but the owner has joined
+const unjoinedRoom = `NOT EXISTS (
+	SELECT 1 FROM joined_rooms AS jr
+	WHERE jr.room_id = r.id AND jr.user_id != r.owner
+)`
+
+func (s *RoomService) queueStatus(ctx context.Context, room models.Room) (status QueueStatus, err error) {
+	status.RoomID = fmt.Sprint(room.ID)
+	if room.ArchivedAt != nil {
+		return
+	}
+
+	// Waiting rooms created up to and including this one
+	status.Position, err = s.DB.NewSelect().
+		TableExpr("rooms AS r").
+		Where("r.archived_at IS NULL").
+		Where(unjoinedRoom).
+		Where("(r.created_at, r.id) <= (?, ?)", room.CreatedAt, room.ID).
+		Count(ctx)
+	if err != nil {
+		return
+	}
+
+	// Position is zero if the room itself has been joined already
+	var waiting bool
+	waiting, err = s.DB.NewSelect().
+		TableExpr("rooms AS r").
+		Where("r.id = ?", room.ID).
+		Where(unjoinedRoom).
+		Exists(ctx)
+	if err != nil || !waiting {
+		status.Position = 0
+		return
+	}
+	status.Waiting = true
+
+	var averageWait sql.NullFloat64
+	if averageWait, err = s.averageWait(ctx); err != nil {
+		return
+	}
+
+	status.EstimatedWait = waitEstimate(averageWait, status.Position, room.CreatedAt)
+	return
+}
 
+// averageWait returns the average time in seconds recent rooms waited until an agent joined
+func (s *RoomService) averageWait(ctx context.Context) (seconds sql.NullFloat64, err error) {
+	recent := s.DB.NewSelect().
+		TableExpr("rooms AS r").
+		Join("JOIN joined_rooms AS jr ON jr.room_id = r.id AND jr.user_id != r.owner").
+		ColumnExpr("MIN(r.created_at) AS created_at").
+		ColumnExpr("MIN(jr.joined_at) AS first_join").
+		Where("jr.joined_at IS NOT NULL").
+		Group("r.id").
+		Having("MIN(jr.joined_at) > ?", time.Now().Add(-waitEstimateWindow)).
+		OrderExpr("first_join DESC").
+		Limit(waitEstimateSamples)
+
+	err = s.DB.NewSelect().
+		TableExpr("(?) AS recent", recent).
+		ColumnExpr("EXTRACT(EPOCH FROM AVG(recent.first_join - recent.created_at))").
+		Scan(ctx, &seconds)
 	return
 }
-*/
+
+func (s *RoomService) publishAssignment(ctx context.Context, agentSessionID string, room models.Room) {
+	evt := RoomEvent{
+		Type: events.TypeRoomAssigned,
+		Room: RoomFromModel(room),
+	}
+
+	if err := s.Events.Publish(ctx, events.AgentTopic(agentSessionID), evt.Type, evt); err != nil {
+		zap.L().Error("failed to publish room assignment", zap.Error(err))
+	}
+	s.publishQueueEvent(ctx, events.TypeRoomAssigned, room)
+}
+
+// publishTags announces the current tags of the room to support personnel
+func (s *RoomService) publishTags(ctx context.Context, room models.Room) {
+	evt := RoomEvent{
+		Type: events.TypeRoomTagged,
+		Room: RoomFromModel(room),
+	}
+
+	err := s.DB.NewSelect().
+		TableExpr("room_tags AS rt").
+		Join("JOIN tags AS t ON t.id = rt.tag_id").
+		ColumnExpr("t.name").
+		Where("rt.room_id = ?", room.ID).
+		Order("t.name").
+		Scan(ctx, &evt.Room.Tags)
+	if err != nil {
+		zap.L().Error("failed to load room tags", zap.Error(err))
+		return
+	}
+
+	if err = s.Events.Publish(ctx, events.QueueTopic, evt.Type, evt); err != nil {
+		zap.L().Error("failed to publish room event", zap.String("type", evt.Type), zap.Error(err))
+	}
+}
+
+func (s *RoomService) publishQueueEvent(ctx context.Context, eventType string, room models.Room) {
+	evt := RoomEvent{
+		Type: eventType,
+		Room: RoomFromModel(room),
+	}
+
+	if err := s.Events.Publish(ctx, events.QueueTopic, eventType, evt); err != nil {
+		zap.L().Error("failed to publish room event", zap.String("type", eventType), zap.Error(err))
+	}
+}
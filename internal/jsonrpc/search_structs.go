@@ -0,0 +1,21 @@
+package jsonrpc
+
+import "time"
+
+// Sent by client, all fields are optional
+type SearchFilters struct {
+	RoomID     string     `json:"roomId"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	SenderType *uint      `json:"senderType"`
+	Tags       []string   `json:"tags"`
+	Limit      int        `json:"limit"`
+}
+
+// Highlight is the matching part of the message, HTML-escaped with matches
+// wrapped in <mark> elements
+type SearchHit struct {
+	Message   Message `json:"message"`
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/cctx"
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Matches are delimited by private use characters, which are swapped for mark tags
+// once the fragment has been escaped, so clients can render the result as HTML
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+const searchHighlight = `ts_headline('simple',
+	?TableAlias.message,
+	search_query,
+	'StartSel=` + highlightStart + `, StopSel=` + highlightStop + `, MaxFragments=2, MaxWords=20, MinWords=5'
+) AS highlight`
+
+var highlightReplacer = strings.NewReplacer(
+	highlightStart, "<mark>",
+	highlightStop, "</mark>",
+)
+
+// renderHighlight escapes the highlighted fragment and marks the matches
+func renderHighlight(fragment string) string {
+	return highlightReplacer.Replace(html.EscapeString(fragment))
+}
+
+func NewSearchService(db *bun.DB, hub *events.Hub) *SearchService {
+	return &SearchService{
+		baseService: baseService{
+			DB:     db,
+			Events: hub,
+		},
+	}
+}
+
+type SearchService struct {
+	baseService
+}
+
+type searchEntry struct {
+	models.Message `bun:",extend"`
+
+	Rank      float64 `bun:",scanonly"`
+	Highlight string  `bun:",scanonly"`
+}
+
+// Messages finds messages matching the query, best matches first. Customers only
+// search their own rooms and never see internal messages.
+func (s *SearchService) Messages(ctx context.Context, query string, filters *SearchFilters) (hits []SearchHit, err error) {
+	hits = make([]SearchHit, 0)
+	sid := ctx.Value(cctx.SessionID).(string)
+	supportPersonnel := ctx.Value(cctx.SupportPersonnel).(bool)
+
+	if strings.TrimSpace(query) == "" {
+		err = fmt.Errorf("query must not be empty")
+		return
+	}
+
+	if filters == nil {
+		filters = &SearchFilters{}
+	}
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	} else if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var entries []searchEntry
+	selectQuery := s.DB.NewSelect().
+		Model(&entries).
+		ColumnExpr("?TableColumns").
+		ColumnExpr("ts_rank(?TableAlias.search_vector, search_query) AS rank").
+		ColumnExpr(searchHighlight).
+		Join("CROSS JOIN websearch_to_tsquery('simple', ?) AS search_query", query).
+		Join("JOIN rooms AS r ON r.id = ?TableAlias.room_id").
+		Where("?TableAlias.search_vector @@ search_query").
+		Where("?TableAlias.deleted_at IS NULL").
+		OrderExpr("rank DESC, ?TableAlias.id DESC").
+		Limit(limit)
+
+	if !supportPersonnel {
+		if len(filters.Tags) > 0 {
+			err = fmt.Errorf("tag filters are only available to support personnel")
+			return
+		}
+
+		selectQuery = selectQuery.
+			Where("r.owner = ?", sid).
+			Where("?TableAlias.user_type != ?", models.UserTypeInternal)
+	}
+
+	if filters.RoomID != "" {
+		var intRoomID int
+		if intRoomID, err = strconv.Atoi(filters.RoomID); err != nil {
+			return
+		}
+		selectQuery = selectQuery.Where("r.id = ?", intRoomID)
+	}
+	if filters.From != nil {
+		selectQuery = selectQuery.Where("?TableAlias.timestamp >= ?", *filters.From)
+	}
+	if filters.To != nil {
+		selectQuery = selectQuery.Where("?TableAlias.timestamp < ?", *filters.To)
+	}
+	if filters.SenderType != nil {
+		selectQuery = selectQuery.Where("?TableAlias.user_type = ?", *filters.SenderType)
+	}
+	for _, tag := range filters.Tags {
+		selectQuery = selectQuery.Where(`EXISTS (
+			SELECT 1 FROM room_tags AS rt
+			JOIN tags AS t ON t.id = rt.tag_id
+			WHERE rt.room_id = r.id AND t.name = ?
+		)`, normalizeTag(tag))
+	}
+
+	if err = selectQuery.Scan(ctx); err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		hits = append(hits, SearchHit{
+			Message:   MessageFromModel(entry.Message),
+			Rank:      entry.Rank,
+			Highlight: renderHighlight(entry.Highlight),
+		})
+	}
+	return
+}
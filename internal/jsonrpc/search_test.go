@@ -0,0 +1,12 @@
+package jsonrpc
+
+import "testing"
+
+func TestRenderHighlight(t *testing.T) {
+	fragment := "<b>" + highlightStart + "refund" + highlightStop + "</b> & more"
+
+	want := "&lt;b&gt;<mark>refund</mark>&lt;/b&gt; &amp; more"
+	if got := renderHighlight(fragment); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
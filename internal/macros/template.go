@@ -0,0 +1,53 @@
+package macros
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	CustomerName = "customer.name"
+	RoomID       = "room.id"
+	AgentName    = "agent.name"
+)
+
+// Placeholders lists the names which may be used in templates
+var Placeholders = []string{
+	CustomerName,
+	RoomID,
+	AgentName,
+}
+
+// Matches {{name}} and {{name|fallback}}, the fallback being used when the value is empty
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z_.]+)\s*(?:\|([^}]*))?\}\}`)
+
+// Validate fails if the template refers to unknown placeholders
+func Validate(template string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !known(match[1]) {
+			return fmt.Errorf("unknown placeholder: %s", match[1])
+		}
+	}
+	return nil
+}
+
+// Render replaces placeholders in the template with given values
+func Render(template string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		if value := values[match[1]]; value != "" {
+			return value
+		}
+		return strings.TrimSpace(match[2])
+	})
+}
+
+func known(name string) bool {
+	for _, placeholder := range Placeholders {
+		if placeholder == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package macros
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	values := map[string]string{
+		CustomerName: "Alice",
+		RoomID:       "42",
+	}
+
+	tests := map[string]string{
+		"Hi {{customer.name}}!":                  "Hi Alice!",
+		"Room {{ room.id }}":                     "Room 42",
+		"I am {{agent.name|your agent}}":         "I am your agent",
+		"Hi {{customer.name|there}}":             "Hi Alice",
+		"Hello {{agent.name}}, no placeholders.": "Hello , no placeholders.",
+	}
+
+	for template, expected := range tests {
+		if rendered := Render(template, values); rendered != expected {
+			t.Errorf("Render(%q) = %q, expected %q", template, rendered, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("Hi {{customer.name|there}}, this is {{agent.name}} in {{room.id}}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate("Hi {{customer.email}}"); err == nil {
+		t.Fatal("expected unknown placeholder to be rejected")
+	}
+}
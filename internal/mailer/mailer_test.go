@@ -0,0 +1,191 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyMailer fails a number of times before delivering
+type flakyMailer struct {
+	MemoryMailer
+
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyMailer) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mu.Unlock()
+		return errors.New("temporary failure")
+	}
+	f.mu.Unlock()
+
+	return f.MemoryMailer.Send(ctx, msg)
+}
+
+func TestQueueRetries(t *testing.T) {
+	mailer := &flakyMailer{failures: 2}
+	queue := NewQueue(mailer, 1)
+	queue.Backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = queue.Run(ctx) }()
+
+	done := make(chan error, 1)
+	if err := queue.Enqueue(Message{To: "customer@example.com", Subject: "Hello"}, func(err error) { done <- err }); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil || len(mailer.Sent()) != 1 {
+			t.Fatalf("expected delivery, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	queue := NewQueue(&flakyMailer{failures: 3}, 1)
+	queue.Backoff = time.Millisecond
+	queue.MaxAttempts = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = queue.Run(ctx) }()
+
+	done := make(chan error, 1)
+	if err := queue.Enqueue(Message{}, func(err error) { done <- err }); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected delivery to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+}
+
+// recipientMailer always fails for one recipient
+type recipientMailer struct {
+	MemoryMailer
+
+	failing string
+}
+
+func (r *recipientMailer) Send(ctx context.Context, msg Message) error {
+	if msg.To == r.failing {
+		return errors.New("mailbox unavailable")
+	}
+	return r.MemoryMailer.Send(ctx, msg)
+}
+
+func TestQueueFailingRecipientDoesNotBlockOthers(t *testing.T) {
+	queue := NewQueue(&recipientMailer{failing: "bad@example.com"}, 2)
+	queue.Backoff = time.Hour
+	queue.Workers = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = queue.Run(ctx) }()
+
+	if err := queue.Enqueue(Message{To: "bad@example.com"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	if err := queue.Enqueue(Message{To: "good@example.com"}, func(err error) { done <- err }); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected delivery, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("good recipient waited for the failing one")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	queue := NewQueue(NewMemoryMailer(), 1)
+
+	if err := queue.Enqueue(Message{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Enqueue(Message{}, nil); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected full queue, got %v", err)
+	}
+}
+
+func TestRenderTranscript(t *testing.T) {
+	type line struct {
+		Timestamp time.Time
+		Role      string
+		Message   string
+		Deleted   bool
+	}
+
+	text, html, err := Render("transcript", map[string]interface{}{
+		"RoomID":       1,
+		"CustomerName": "Alice",
+		"Lines": []line{
+			{Timestamp: time.Now(), Role: "customer", Message: "<script>"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(text, "customer: <script>") {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Fatalf("message was not escaped:\n%s", html)
+	}
+}
+
+func TestSMTPMailerTimesOut(t *testing.T) {
+	// Accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	}()
+
+	mailer, err := NewSMTPMailer(listener.Addr().String(), "helpify@example.com", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer.Timeout = 50 * time.Millisecond
+
+	started := time.Now()
+	if err = mailer.Send(context.Background(), Message{To: "customer@example.com"}); err == nil {
+		t.Fatal("expected send to fail")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected send to give up quickly, took %s", elapsed)
+	}
+}
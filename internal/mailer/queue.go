@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrQueueFull = errors.New("mail queue is full")
+
+// Queue sends emails in the background, retrying failed deliveries with
+// exponential backoff. Failed emails wait for their next attempt outside of the
+// queue, so they never hold up others. Emails still queued on shutdown are lost.
+type Queue struct {
+	Mailer      Mailer
+	MaxAttempts int
+	Backoff     time.Duration
+	// Number of emails sent at once
+	Workers int
+
+	jobs    chan job
+	retries chan job
+}
+
+type job struct {
+	msg      Message
+	done     func(err error)
+	attempts int
+}
+
+func NewQueue(mailer Mailer, size int) *Queue {
+	return &Queue{
+		Mailer:      mailer,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		Workers:     4,
+		jobs:        make(chan job, size),
+		retries:     make(chan job),
+	}
+}
+
+// Enqueue schedules the email for delivery. done, if not nil, is called from the
+// queue once the email was sent or given up on; it is never called for emails
+// lost on shutdown.
+func (q *Queue) Enqueue(msg Message, done func(err error)) error {
+	select {
+	case q.jobs <- job{msg: msg, done: done}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < q.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-q.jobs:
+			q.attempt(ctx, j)
+		case j := <-q.retries:
+			q.attempt(ctx, j)
+		}
+	}
+}
+
+// attempt tries to deliver the email once, scheduling the next attempt if it fails
+func (q *Queue) attempt(ctx context.Context, j job) {
+	j.attempts++
+
+	err := q.Mailer.Send(ctx, j.msg)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil && j.attempts < q.MaxAttempts {
+		backoff := q.Backoff << (j.attempts - 1)
+		zap.L().Warn("failed to send email, retrying", zap.String("subject", j.msg.Subject), zap.Duration("backoff", backoff), zap.Error(err))
+
+		time.AfterFunc(backoff, func() {
+			select {
+			case q.retries <- j:
+			case <-ctx.Done():
+			}
+		})
+		return
+	} else if err != nil {
+		zap.L().Error("giving up on email", zap.String("subject", j.msg.Subject), zap.Int("attempts", j.attempts), zap.Error(err))
+	}
+
+	if j.done != nil {
+		j.done(err)
+	}
+}
@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// SMTPMailer delivers emails through an SMTP server
+type SMTPMailer struct {
+	// Server address as host:port
+	Addr string
+	From string
+	Auth smtp.Auth
+	// Upper limit for delivering a single email, including connecting
+	Timeout time.Duration
+}
+
+// NewSMTPMailer returns a mailer for given server. Authentication is skipped
+// without a username.
+func NewSMTPMailer(addr string, from string, username string, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	if _, err = mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	m := &SMTPMailer{
+		Addr:    addr,
+		From:    from,
+		Timeout: 30 * time.Second,
+	}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	var from *mail.Address
+	if from, err = mail.ParseAddress(m.From); err != nil {
+		return
+	}
+
+	var body []byte
+	if body, err = m.encode(msg); err != nil {
+		return
+	}
+
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	if conn, err = dialer.DialContext(ctx, "tcp", m.Addr); err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Unblock pending reads and writes if ctx is cancelled early
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	return m.send(conn, from.Address, msg.To, body)
+}
+
+// send runs the SMTP conversation the same way smtp.SendMail does, but on a
+// connection with deadlines
+func (m *SMTPMailer) send(conn net.Conn, from string, to string, body []byte) (err error) {
+	host, _, _ := net.SplitHostPort(m.Addr)
+
+	var c *smtp.Client
+	if c, err = smtp.NewClient(conn, host); err != nil {
+		return
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return
+		}
+	}
+
+	if m.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server does not support authentication")
+		}
+		if err = c.Auth(m.Auth); err != nil {
+			return
+		}
+	}
+
+	if err = c.Mail(from); err != nil {
+		return
+	}
+	if err = c.Rcpt(to); err != nil {
+		return
+	}
+
+	var w io.WriteCloser
+	if w, err = c.Data(); err != nil {
+		return
+	}
+	if _, err = w.Write(body); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	return c.Quit()
+}
+
+// encode builds a multipart/alternative message with the text part first, so
+// clients prefer the HTML part
+func (m *SMTPMailer) encode(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl"))
+)
+
+// Render executes both the plain text and HTML template of given name, such as
+// "transcript" for templates/transcript.txt.tmpl and templates/transcript.html.tmpl
+func Render(name string, data interface{}) (text string, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+
+	if err = textTemplates.ExecuteTemplate(&textBuf, name+".txt.tmpl", data); err != nil {
+		return
+	}
+	if err = htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html.tmpl", data); err != nil {
+		return
+	}
+
+	text, html = textBuf.String(), htmlBuf.String()
+	return
+}
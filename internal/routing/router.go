@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+// Router assigns rooms to support agents. A nil router or strategy leaves rooms
+// in the queue for agents to join manually.
+type Router struct {
+	Strategy Strategy
+}
+
+func NewRouter(strategy Strategy) *Router {
+	return &Router{
+		Strategy: strategy,
+	}
+}
+
+// openRoomsExpr counts the unarchived rooms assigned to agent a
+const openRoomsExpr = `(
+	SELECT COUNT(*) FROM joined_rooms AS jr
+	JOIN rooms AS r ON r.id = jr.room_id
+	WHERE jr.user_id = a.session_id
+	AND jr.assigned_at IS NOT NULL
+	AND r.archived_at IS NULL
+)`
+
+// Assign picks an agent for the room and records the assignment in joined_rooms.
+// Returns nil if no agent could be picked, leaving the room in the queue. db
+// should be a transaction, the picked agent stays locked until it ends.
+func (r *Router) Assign(ctx context.Context, db bun.IDB, room models.Room) (assigned *Candidate, err error) {
+	if r == nil || r.Strategy == nil {
+		return
+	}
+
+	var candidates []Candidate
+	if candidates, err = r.candidates(ctx, db, room); err != nil {
+		return
+	}
+	candidates = available(candidates)
+
+	// Workloads may have changed since they were read, so the picked agent is
+	// locked and checked again. Agents who turn out to be full are skipped.
+	for {
+		if assigned = r.Strategy.Pick(candidates); assigned == nil {
+			return
+		}
+
+		var hasCapacity bool
+		if hasCapacity, err = lockWithCapacity(ctx, db, assigned.AgentID); err != nil {
+			return
+		} else if hasCapacity {
+			break
+		}
+		candidates = without(candidates, assigned.AgentID)
+	}
+
+	now := time.Now()
+	joinedRoom := models.JoinedRoom{
+		UserID:     assigned.SessionID,
+		RoomID:     room.ID,
+		UserType:   models.UserTypeSupport,
+		AssignedAt: &now,
+	}
+
+	_, err = db.NewInsert().
+		Model(&joinedRoom).
+		On("CONFLICT (user_id, room_id) DO UPDATE").
+		Set("assigned_at = EXCLUDED.assigned_at").
+		Exec(ctx)
+	return
+}
+
+// candidates returns the online agents along with their workload
+func (r *Router) candidates(ctx context.Context, db bun.IDB, room models.Room) (candidates []Candidate, err error) {
+	err = db.NewSelect().
+		TableExpr("agents AS a").
+		ColumnExpr("a.id AS agent_id").
+		ColumnExpr("a.session_id").
+		ColumnExpr("a.max_rooms").
+		ColumnExpr(openRoomsExpr+" AS open_rooms").
+		ColumnExpr(`(
+			SELECT MAX(jr.assigned_at) FROM joined_rooms AS jr
+			WHERE jr.user_id = a.session_id
+		) AS last_assigned_at`).
+		ColumnExpr(`(
+			SELECT MAX(jr.assigned_at) FROM joined_rooms AS jr
+			JOIN rooms AS r ON r.id = jr.room_id
+			WHERE jr.user_id = a.session_id
+			AND r.owner = ?
+			AND r.id != ?
+		) AS last_served_customer_at`, room.Owner, room.ID).
+		Where("a.status = ?", models.AgentOnline).
+		Scan(ctx, &candidates)
+	return
+}
+
+// lockWithCapacity locks the agent row for the rest of the transaction, reporting
+// whether the agent is still online and below capacity
+func lockWithCapacity(ctx context.Context, db bun.IDB, agentID uint) (hasCapacity bool, err error) {
+	err = db.NewSelect().
+		TableExpr("agents AS a").
+		ColumnExpr("a.status = ? AND "+openRoomsExpr+" < a.max_rooms", models.AgentOnline).
+		Where("a.id = ?", agentID).
+		For("UPDATE OF a").
+		Scan(ctx, &hasCapacity)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	return
+}
+
+func without(candidates []Candidate, agentID uint) (filtered []Candidate) {
+	for _, candidate := range candidates {
+		if candidate.AgentID != agentID {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return
+}
+
+// available filters out agents who are at their capacity
+func available(candidates []Candidate) (filtered []Candidate) {
+	for _, candidate := range candidates {
+		if candidate.Load < candidate.MaxRooms {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return
+}
@@ -0,0 +1,14 @@
+package routing
+
+import "testing"
+
+func TestAvailableSkipsAgentsAtCapacity(t *testing.T) {
+	filtered := available([]Candidate{
+		{AgentID: 1, Load: 2, MaxRooms: 2},
+		{AgentID: 2, Load: 1, MaxRooms: 2},
+		{AgentID: 3, Load: 0, MaxRooms: 0},
+	})
+	if len(filtered) != 1 || filtered[0].AgentID != 2 {
+		t.Fatalf("expected only agent 2, got %+v", filtered)
+	}
+}
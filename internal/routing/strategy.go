@@ -0,0 +1,122 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Candidate is an agent which may be assigned a new room
+type Candidate struct {
+	AgentID   uint   `bun:"agent_id"`
+	SessionID string `bun:"session_id"`
+	// Open rooms currently assigned to the agent
+	Load int `bun:"open_rooms"`
+	// Open rooms the agent is willing to handle at once
+	MaxRooms int `bun:"max_rooms"`
+	// Latest assignment of any room to the agent
+	LastAssignedAt *time.Time `bun:"last_assigned_at"`
+	// Latest assignment of a room owned by the same customer to the agent
+	LastServedCustomerAt *time.Time `bun:"last_served_customer_at"`
+}
+
+// Strategy picks an agent among the candidates, or nil if none is suitable
+type Strategy interface {
+	Pick(candidates []Candidate) *Candidate
+}
+
+// ParseStrategy returns the strategy known by given name. "manual" disables
+// routing and yields a nil strategy.
+func ParseStrategy(name string) (strategy Strategy, err error) {
+	switch name {
+	case "manual":
+	case "round-robin":
+		strategy = RoundRobin{}
+	case "least-loaded":
+		strategy = LeastLoaded{}
+	case "sticky":
+		strategy = Sticky{Fallback: LeastLoaded{}}
+	default:
+		err = fmt.Errorf("unsupported routing strategy: %s", name)
+	}
+	return
+}
+
+// RoundRobin picks the agent who has waited longest for an assignment. Unlike an
+// in-memory cursor, this stays fair across several instances.
+type RoundRobin struct{}
+
+func (RoundRobin) Pick(candidates []Candidate) *Candidate {
+	return pick(candidates, func(a, b *Candidate) bool {
+		return assignedBefore(a, b)
+	})
+}
+
+// LeastLoaded picks the agent with the fewest open rooms
+type LeastLoaded struct{}
+
+func (LeastLoaded) Pick(candidates []Candidate) *Candidate {
+	return pick(candidates, func(a, b *Candidate) bool {
+		if a.Load != b.Load {
+			return a.Load < b.Load
+		}
+		return assignedBefore(a, b)
+	})
+}
+
+// Sticky picks the agent who most recently served the same customer, falling
+// back to another strategy for new customers
+type Sticky struct {
+	Fallback Strategy
+}
+
+func (s Sticky) Pick(candidates []Candidate) *Candidate {
+	var previous *Candidate
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.LastServedCustomerAt == nil {
+			continue
+		}
+		if previous == nil || candidate.LastServedCustomerAt.After(*previous.LastServedCustomerAt) {
+			previous = candidate
+		}
+	}
+
+	if previous != nil || s.Fallback == nil {
+		return previous
+	}
+	return s.Fallback.Pick(candidates)
+}
+
+// pick returns the first candidate according to less, using agent id as tie breaker
+func pick(candidates []Candidate, less func(a, b *Candidate) bool) *Candidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sorted := make([]Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := &sorted[i], &sorted[j]
+		if less(a, b) {
+			return true
+		} else if less(b, a) {
+			return false
+		}
+		return a.AgentID < b.AgentID
+	})
+
+	return &sorted[0]
+}
+
+// assignedBefore reports whether a was last assigned a room before b. Agents
+// who were never assigned come first.
+func assignedBefore(a, b *Candidate) bool {
+	switch {
+	case a.LastAssignedAt == nil:
+		return b.LastAssignedAt != nil
+	case b.LastAssignedAt == nil:
+		return false
+	}
+	return a.LastAssignedAt.Before(*b.LastAssignedAt)
+}
@@ -0,0 +1,68 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundRobinPicksLongestWaiting(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+
+	picked := RoundRobin{}.Pick([]Candidate{
+		{AgentID: 1, LastAssignedAt: &now},
+		{AgentID: 2, LastAssignedAt: &earlier},
+		{AgentID: 3, LastAssignedAt: &now},
+	})
+	if picked == nil || picked.AgentID != 2 {
+		t.Fatalf("expected agent 2, got %+v", picked)
+	}
+
+	picked = RoundRobin{}.Pick([]Candidate{
+		{AgentID: 2, LastAssignedAt: &earlier},
+		{AgentID: 3},
+	})
+	if picked == nil || picked.AgentID != 3 {
+		t.Fatalf("expected never assigned agent 3, got %+v", picked)
+	}
+}
+
+func TestLeastLoadedPicksFewestRooms(t *testing.T) {
+	picked := LeastLoaded{}.Pick([]Candidate{
+		{AgentID: 1, Load: 3},
+		{AgentID: 2, Load: 1},
+		{AgentID: 3, Load: 1},
+	})
+	if picked == nil || picked.AgentID != 2 {
+		t.Fatalf("expected agent 2, got %+v", picked)
+	}
+}
+
+func TestStickyPrefersPreviousAgent(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	strategy := Sticky{Fallback: LeastLoaded{}}
+
+	picked := strategy.Pick([]Candidate{
+		{AgentID: 1, Load: 0},
+		{AgentID: 2, Load: 5, LastServedCustomerAt: &earlier},
+		{AgentID: 3, Load: 5, LastServedCustomerAt: &now},
+	})
+	if picked == nil || picked.AgentID != 3 {
+		t.Fatalf("expected agent 3, got %+v", picked)
+	}
+
+	picked = strategy.Pick([]Candidate{
+		{AgentID: 1, Load: 2},
+		{AgentID: 2, Load: 0},
+	})
+	if picked == nil || picked.AgentID != 2 {
+		t.Fatalf("expected fallback to agent 2, got %+v", picked)
+	}
+}
+
+func TestPickWithoutCandidates(t *testing.T) {
+	if picked := (LeastLoaded{}).Pick(nil); picked != nil {
+		t.Fatalf("expected no candidate, got %+v", picked)
+	}
+}
@@ -55,11 +55,13 @@ var (
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
 	_ Error = new(internalServerError)
+	_ Error = new(forbiddenError)
 )
 
 const (
 	errcodeDefault                  = -32000
 	errcodeNotificationsUnsupported = -32001
+	errcodeForbidden                = -32003
 	errcodePanic                    = -32603
 	errcodeMarshalError             = -32603
 )
@@ -117,3 +119,9 @@ type internalServerError struct {
 func (e *internalServerError) ErrorCode() int { return e.code }
 
 func (e *internalServerError) Error() string { return e.message }
+
+type forbiddenError struct{ message string }
+
+func (e *forbiddenError) ErrorCode() int { return errcodeForbidden }
+
+func (e *forbiddenError) Error() string { return e.message }
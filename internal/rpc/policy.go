@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Policy authorizes a call before its callback runs. params holds the decoded
+// positional arguments of the call, with omitted optional arguments set to their
+// zero values. Returning an error rejects the call with errcodeForbidden.
+type Policy func(ctx context.Context, params []interface{}) error
+
+// Policies maps method and subscription names of a service, as exposed over RPC
+// (e.g. "send" for "chat_send"), to their policy.
+type Policies map[string]Policy
+
+// AllowAll is a Policy which accepts every call.
+func AllowAll(ctx context.Context, params []interface{}) error {
+	return nil
+}
+
+// RequirePolicies makes the server refuse to register services without a policy
+// for each of their methods and subscriptions.
+func (s *Server) RequirePolicies() {
+	s.services.mu.Lock()
+	defer s.services.mu.Unlock()
+	s.services.requirePolicies = true
+}
+
+// RegisterNameWithPolicies is like RegisterName, but calls are authorized by given
+// policies before running. Every method and subscription of the receiver must have
+// a policy.
+func (s *Server) RegisterNameWithPolicies(name string, receiver interface{}, policies Policies) error {
+	if policies == nil {
+		policies = Policies{}
+	}
+	return s.services.registerNameWithPolicies(name, receiver, policies)
+}
+
+// assignPolicies attaches policies to the callbacks of a service.
+func assignPolicies(service string, callbacks map[string]*callback, policies Policies) error {
+	for name := range policies {
+		if _, ok := callbacks[name]; !ok {
+			return fmt.Errorf("policy for unknown method %s%s%s", service, serviceMethodSeparator, name)
+		}
+	}
+	for name, cb := range callbacks {
+		policy, ok := policies[name]
+		if !ok || policy == nil {
+			return fmt.Errorf("no policy for method %s%s%s", service, serviceMethodSeparator, name)
+		}
+		cb.policy = policy
+	}
+	return nil
+}
+
+// authorize runs the policy of the callback, if it has one.
+func (c *callback) authorize(ctx context.Context, args []reflect.Value) error {
+	if c.policy == nil {
+		return nil
+	}
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		params[i] = arg.Interface()
+	}
+	if err := c.policy(ctx, params); err != nil {
+		return &forbiddenError{message: err.Error()}
+	}
+	return nil
+}
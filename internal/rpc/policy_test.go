@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type policyTestService struct{}
+
+func (s *policyTestService) Public() string { return "public" }
+
+func (s *policyTestService) Secret(name string) string { return "secret " + name }
+
+func newPolicyTestServer(t *testing.T) *Server {
+	server := NewServer()
+	server.RequirePolicies()
+
+	err := server.RegisterNameWithPolicies("policy", new(policyTestService), Policies{
+		"public": AllowAll,
+		"secret": func(ctx context.Context, params []interface{}) error {
+			if params[0].(string) != "alice" {
+				return errors.New("not allowed")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestPolicyEnforced(t *testing.T) {
+	server := newPolicyTestServer(t)
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result string
+	if err := client.Call(&result, "policy_public"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(&result, "policy_secret", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.Call(&result, "policy_secret", "bob")
+	if err == nil {
+		t.Fatal("expected call to be rejected")
+	}
+	var rpcErr Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	if rpcErr.ErrorCode() != errcodeForbidden {
+		t.Fatalf("wrong error code %d, want %d", rpcErr.ErrorCode(), errcodeForbidden)
+	}
+}
+
+func TestPolicyRequired(t *testing.T) {
+	server := NewServer()
+	server.RequirePolicies()
+
+	if err := server.RegisterName("policy", new(policyTestService)); err == nil {
+		t.Fatal("expected registration without policies to fail")
+	}
+
+	err := server.RegisterNameWithPolicies("policy", new(policyTestService), Policies{
+		"public": AllowAll,
+	})
+	if err == nil {
+		t.Fatal("expected registration with missing policy to fail")
+	}
+
+	err = server.RegisterNameWithPolicies("policy", new(policyTestService), Policies{
+		"public": AllowAll,
+		"secret": AllowAll,
+		"typo":   AllowAll,
+	})
+	if err == nil {
+		t.Fatal("expected registration with unknown policy to fail")
+	}
+}
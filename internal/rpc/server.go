@@ -55,7 +55,9 @@ func NewServer() *Server {
 	// Register the default service providing meta information about the RPC service such
 	// as the services and methods it offers.
 	rpcService := &RPCService{server}
-	server.RegisterName(MetadataApi, rpcService)
+	server.RegisterNameWithPolicies(MetadataApi, rpcService, Policies{
+		"modules": AllowAll,
+	})
 	return server
 }
 
@@ -73,6 +75,13 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.ServeCodecContext(context.Background(), codec, options)
+}
+
+// ServeCodecContext is like ServeCodec, but method handlers receive a context derived
+// from ctx. This allows values attached to the originating request (e.g. session
+// information) to be available for the lifetime of the connection.
+func (s *Server) ServeCodecContext(ctx context.Context, codec ServerCodec, options CodecOption) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -84,7 +93,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(ctx, codec, s.idgen, &s.services)
 	<-codec.closed()
 	c.Close()
 }
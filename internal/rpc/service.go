@@ -36,8 +36,9 @@ var (
 )
 
 type serviceRegistry struct {
-	mu       sync.Mutex
-	services map[string]service
+	mu              sync.Mutex
+	services        map[string]service
+	requirePolicies bool // reject services registered without policies
 }
 
 // service represents a registered object.
@@ -55,9 +56,16 @@ type callback struct {
 	hasCtx      bool           // method's first argument is a context (not included in argTypes)
 	errPos      int            // err return idx, of -1 when method cannot return error
 	isSubscribe bool           // true if this is a subscription callback
+	policy      Policy         // authorizes calls before they run, may be nil
 }
 
 func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
+	return r.registerNameWithPolicies(name, rcvr, nil)
+}
+
+// registerNameWithPolicies registers the service. If policies is non-nil, every
+// callback must have one.
+func (r *serviceRegistry) registerNameWithPolicies(name string, rcvr interface{}, policies Policies) error {
 	rcvrVal := reflect.ValueOf(rcvr)
 	if name == "" {
 		return fmt.Errorf("no service name for type %s", rcvrVal.Type().String())
@@ -69,6 +77,14 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if policies == nil && r.requirePolicies {
+		return fmt.Errorf("service %s registered without policies", name)
+	}
+	if policies != nil {
+		if err := assignPolicies(name, callbacks, policies); err != nil {
+			return err
+		}
+	}
 	if r.services == nil {
 		r.services = make(map[string]service)
 	}
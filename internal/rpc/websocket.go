@@ -67,7 +67,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 
 func (s *Server) HandleWebsocketConnection(r *http.Request, conn *websocket.Conn) {
 	codec := newWebsocketCodec(conn, r.Host, r.Header)
-	s.ServeCodec(codec, 0)
+	s.ServeCodecContext(r.Context(), codec, 0)
 }
 
 // wsHandshakeValidator returns a handler that verifies the origin during the
@@ -0,0 +1,107 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner streams files to a clamd daemon using the INSTREAM command
+type ClamdScanner struct {
+	// "tcp" or "unix"
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+func NewClamdScanner(network string, address string) *ClamdScanner {
+	return &ClamdScanner{
+		Network: network,
+		Address: address,
+		Timeout: 30 * time.Second,
+	}
+}
+
+func (c *ClamdScanner) Name() string {
+	return "clamd"
+}
+
+func (c *ClamdScanner) Scan(ctx context.Context, file *File) (err error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	if conn, err = dialer.DialContext(ctx, c.Network, c.Address); err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var reply string
+	if reply, err = c.instream(conn, file.Content); err != nil {
+		return
+	}
+
+	// Replies look like "stream: OK" or "stream: <signature> FOUND"
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return reject(c, "malware detected (%s)", strings.TrimSuffix(reply, " FOUND"))
+	default:
+		return fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+}
+
+func (c *ClamdScanner) instream(conn net.Conn, content []byte) (reply string, err error) {
+	w := bufio.NewWriter(conn)
+	if _, err = w.WriteString("zINSTREAM\x00"); err != nil {
+		return
+	}
+
+	size := make([]byte, 4)
+	for len(content) > 0 {
+		chunk := content
+		if len(chunk) > clamdChunkSize {
+			chunk = chunk[:clamdChunkSize]
+		}
+		content = content[len(chunk):]
+
+		binary.BigEndian.PutUint32(size, uint32(len(chunk)))
+		if _, err = w.Write(size); err != nil {
+			return
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return
+		}
+	}
+
+	// Zero length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = w.Write(size); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	if reply, err = bufio.NewReader(conn).ReadString(0); err != nil {
+		return
+	}
+	reply = strings.TrimSuffix(reply, "\x00")
+	return
+}
@@ -0,0 +1,121 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+	xmpHeader    = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// Ancillary PNG chunks which carry metadata rather than pixels
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// EXIFStripper removes EXIF and XMP metadata, which may include camera details
+// and GPS coordinates, from JPEG and PNG images
+type EXIFStripper struct{}
+
+func (EXIFStripper) Name() string {
+	return "exif"
+}
+
+func (s EXIFStripper) Scan(ctx context.Context, file *File) error {
+	var stripped []byte
+	var ok bool
+
+	switch file.ContentType {
+	case "image/jpeg":
+		stripped, ok = stripJPEG(file.Content)
+	case "image/png":
+		stripped, ok = stripPNG(file.Content)
+	default:
+		return nil
+	}
+
+	if !ok {
+		return reject(s, "malformed %s image", file.ContentType)
+	}
+	file.Content = stripped
+	return nil
+}
+
+// stripJPEG drops APP1 segments holding EXIF or XMP data. Everything from the
+// start of scan marker on is image data and copied as is.
+func stripJPEG(data []byte) (out []byte, ok bool) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return
+	}
+	out = append(out, data[:2]...)
+	data = data[2:]
+
+	for {
+		if len(data) < 4 || data[0] != 0xff {
+			return nil, false
+		}
+
+		marker := data[1]
+		// Start of scan or end of image, the rest is not metadata
+		if marker == 0xda || marker == 0xd9 {
+			return append(out, data...), true
+		}
+
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if length < 2 || len(data) < 2+length {
+			return nil, false
+		}
+		segment := data[:2+length]
+		data = data[2+length:]
+
+		payload := segment[4:]
+		if marker == 0xe1 && (bytes.HasPrefix(payload, exifHeader) || bytes.HasPrefix(payload, xmpHeader)) {
+			continue
+		}
+		out = append(out, segment...)
+	}
+}
+
+// stripPNG drops metadata chunks, verifying the checksum of the chunks it keeps
+func stripPNG(data []byte) (out []byte, ok bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return
+	}
+	out = append(out, pngSignature...)
+	data = data[len(pngSignature):]
+
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, false
+		}
+
+		length := binary.BigEndian.Uint32(data[:4])
+		if uint64(len(data)) < 12+uint64(length) {
+			return nil, false
+		}
+		chunk := data[:12+length]
+		data = data[12+length:]
+
+		chunkType := string(chunk[4:8])
+		if crc32.ChecksumIEEE(chunk[4:8+length]) != binary.BigEndian.Uint32(chunk[8+length:]) {
+			return nil, false
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, chunk...)
+		}
+		if chunkType == "IEND" {
+			return out, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,69 @@
+// Package scan checks uploaded files before they are shown to anyone else.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// File is an upload under inspection. Scanners may rewrite the content, for
+// example to remove metadata.
+type File struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Scanner inspects a file, returning a *Rejection if the file must not be
+// published. Any other error means the file could not be checked.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, file *File) error
+}
+
+// Rejection explains why a file was quarantined
+type Rejection struct {
+	Scanner string
+	Reason  string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("%s: %s", r.Scanner, r.Reason)
+}
+
+func reject(scanner Scanner, format string, args ...interface{}) *Rejection {
+	return &Rejection{
+		Scanner: scanner.Name(),
+		Reason:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Pipeline runs scanners in order, stopping at the first one which rejects the file
+type Pipeline struct {
+	Scanners []Scanner
+}
+
+func NewPipeline(scanners ...Scanner) *Pipeline {
+	return &Pipeline{
+		Scanners: scanners,
+	}
+}
+
+// Run scans the file. A nil pipeline accepts everything.
+func (p *Pipeline) Run(ctx context.Context, file *File) (err error) {
+	if p == nil {
+		return
+	}
+
+	for _, scanner := range p.Scanners {
+		if err = scanner.Scan(ctx, file); err != nil {
+			var rejection *Rejection
+			if !errors.As(err, &rejection) {
+				err = fmt.Errorf("%s scanner failed: %w", scanner.Name(), err)
+			}
+			return
+		}
+	}
+	return
+}
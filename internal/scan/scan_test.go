@@ -0,0 +1,148 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestPipelineStopsAtRejection(t *testing.T) {
+	pipeline := NewPipeline(MIMESniffer{}, EXIFStripper{})
+
+	err := pipeline.Run(context.Background(), &File{
+		ContentType: "image/png",
+		Content:     []byte("<html><script>alert(1)</script></html>"),
+	})
+
+	var rejection *Rejection
+	if !errors.As(err, &rejection) || rejection.Scanner != "mime" {
+		t.Fatalf("expected mime rejection, got %v", err)
+	}
+}
+
+func TestEXIFStripperJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert an EXIF segment right after the start of image marker
+	exif := append([]byte("Exif\x00\x00"), []byte("GPS 52.37N 4.89E")...)
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	content := append([]byte{}, encoded.Bytes()[:2]...)
+	content = append(content, segment...)
+	content = append(content, encoded.Bytes()[2:]...)
+
+	file := &File{ContentType: "image/jpeg", Content: content}
+	if err := (EXIFStripper{}).Scan(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(file.Content, []byte("GPS")) {
+		t.Fatal("expected exif data to be removed")
+	}
+	if !bytes.Equal(file.Content, encoded.Bytes()) {
+		t.Fatal("expected image data to be kept")
+	}
+}
+
+func TestEXIFStripperPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	file := &File{ContentType: "image/png", Content: encoded.Bytes()}
+	if err := (EXIFStripper{}).Scan(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(file.Content)); err != nil {
+		t.Fatal(err)
+	}
+
+	file.Content = file.Content[:len(file.Content)-4]
+	var rejection *Rejection
+	if err := (EXIFStripper{}).Scan(context.Background(), file); !errors.As(err, &rejection) {
+		t.Fatalf("expected truncated image to be rejected, got %v", err)
+	}
+}
+
+// fakeClamd answers INSTREAM requests, flagging streams containing "EICAR"
+func fakeClamd(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			if command, _ := r.ReadString(0); command != "zINSTREAM\x00" {
+				_ = conn.Close()
+				continue
+			}
+
+			var content []byte
+			size := make([]byte, 4)
+			for {
+				if _, err = io.ReadFull(r, size); err != nil {
+					break
+				}
+				n := binary.BigEndian.Uint32(size)
+				if n == 0 {
+					break
+				}
+				chunk := make([]byte, n)
+				if _, err = io.ReadFull(r, chunk); err != nil {
+					break
+				}
+				content = append(content, chunk...)
+			}
+
+			reply := "stream: OK\x00"
+			if bytes.Contains(content, []byte("EICAR")) {
+				reply = "stream: Eicar-Test-Signature FOUND\x00"
+			}
+			_, _ = conn.Write([]byte(reply))
+			_ = conn.Close()
+		}
+	}()
+
+	t.Cleanup(func() { _ = listener.Close() })
+	return listener
+}
+
+func TestClamdScanner(t *testing.T) {
+	listener := fakeClamd(t)
+	scanner := NewClamdScanner("tcp", listener.Addr().String())
+	ctx := context.Background()
+
+	clean := bytes.Repeat([]byte("a"), 3*clamdChunkSize/2)
+	if err := scanner.Scan(ctx, &File{Content: clean}); err != nil {
+		t.Fatal(err)
+	}
+
+	infected := append(clean, []byte("EICAR")...)
+	var rejection *Rejection
+	if err := scanner.Scan(ctx, &File{Content: infected}); !errors.As(err, &rejection) {
+		t.Fatalf("expected rejection, got %v", err)
+	} else if rejection.Reason != "malware detected (Eicar-Test-Signature)" {
+		t.Fatalf("unexpected reason %q", rejection.Reason)
+	}
+}
@@ -0,0 +1,32 @@
+package scan
+
+import (
+	"context"
+	"mime"
+	"net/http"
+)
+
+// MIMESniffer rejects files whose content does not look like the declared type,
+// such as HTML uploaded as an image
+type MIMESniffer struct{}
+
+func (MIMESniffer) Name() string {
+	return "mime"
+}
+
+func (s MIMESniffer) Scan(ctx context.Context, file *File) error {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(file.Content))
+	if err != nil {
+		return reject(s, "unrecognized content")
+	}
+
+	declared, _, err := mime.ParseMediaType(file.ContentType)
+	if err != nil {
+		return reject(s, "invalid content type %q", file.ContentType)
+	}
+
+	if detected != declared {
+		return reject(s, "content looks like %s, not %s", detected, declared)
+	}
+	return nil
+}
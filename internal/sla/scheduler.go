@@ -0,0 +1,176 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+
+	"github.com/helpify-project/backend/internal/database/models"
+	"github.com/helpify-project/backend/internal/events"
+)
+
+const (
+	TargetFirstResponse = "firstResponse"
+	TargetNextResponse  = "nextResponse"
+)
+
+// Alert is pushed through the room and queue topics when a room is about to miss
+// or has missed a target. The queue topic reaches support before anyone joined.
+type Alert struct {
+	Type       string     `json:"type"`
+	RoomID     string     `json:"roomId"`
+	Target     string     `json:"target"`
+	Priority   string     `json:"priority"`
+	DueAt      time.Time  `json:"dueAt"`
+	BreachedAt *time.Time `json:"breachedAt,omitempty"`
+}
+
+// Scheduler periodically looks for rooms past their warning or due times. Rooms
+// are claimed with a conditional update, so several instances may run it at once
+// without duplicating alerts.
+type Scheduler struct {
+	DB       *bun.DB
+	Events   *events.Hub
+	Interval time.Duration
+}
+
+func NewScheduler(db *bun.DB, hub *events.Hub) *Scheduler {
+	return &Scheduler{
+		DB:       db,
+		Events:   hub,
+		Interval: 10 * time.Second,
+	}
+}
+
+// timer describes the columns of one kind of SLA timer
+type timer struct {
+	target string
+	prefix string
+	// Next response can be breached again in a later exchange
+	repeating bool
+}
+
+// dueRoom is a room returned from claiming an alert
+type dueRoom struct {
+	ID       uint      `bun:"id"`
+	Priority string    `bun:"priority"`
+	DueAt    time.Time `bun:"due_at"`
+}
+
+var timers = []timer{
+	{target: TargetFirstResponse, prefix: "first_response"},
+	{target: TargetNextResponse, prefix: "next_response", repeating: true},
+}
+
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for _, t := range timers {
+			if err := s.check(ctx, t); err != nil {
+				zap.L().Error("failed to check sla timers", zap.String("target", t.target), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *Scheduler) check(ctx context.Context, t timer) (err error) {
+	now := time.Now()
+	warnAt := bun.Ident(t.prefix + "_warn_at")
+	dueAt := bun.Ident(t.prefix + "_due_at")
+	breachedAt := bun.Ident(t.prefix + "_breached_at")
+
+	var warned []dueRoom
+	_, err = s.DB.NewUpdate().
+		Model((*models.Room)(nil)).
+		Set("? = NULL", warnAt).
+		Where("archived_at IS NULL").
+		Where("? <= ?", warnAt, now).
+		Returning("id, priority, ? AS due_at", dueAt).
+		Exec(ctx, &warned)
+	if err != nil {
+		return
+	}
+
+	var breached []dueRoom
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) (err error) {
+		// The room columns only cache the latest breach
+		breachQuery := tx.NewUpdate().
+			Model((*models.Room)(nil)).
+			Set("? = ?", breachedAt, now).
+			Set("? = NULL", warnAt).
+			Where("archived_at IS NULL").
+			Where("? <= ?", dueAt, now)
+		if t.repeating {
+			breachQuery = breachQuery.Where("(? IS NULL OR ? < ?)", breachedAt, breachedAt, dueAt)
+		} else {
+			breachQuery = breachQuery.Where("? IS NULL", breachedAt)
+		}
+
+		_, err = breachQuery.
+			Returning("id, priority, ? AS due_at", dueAt).
+			Exec(ctx, &breached)
+		if err != nil || len(breached) == 0 {
+			return
+		}
+
+		history := make([]models.SLABreach, 0, len(breached))
+		for _, room := range breached {
+			history = append(history, models.SLABreach{
+				RoomID:     room.ID,
+				Target:     t.target,
+				DueAt:      room.DueAt,
+				BreachedAt: now,
+			})
+		}
+
+		_, err = tx.NewInsert().
+			Model(&history).
+			Exec(ctx)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	for _, room := range warned {
+		s.publish(ctx, room.ID, Alert{
+			Type:     events.TypeSLAWarning,
+			RoomID:   fmt.Sprint(room.ID),
+			Target:   t.target,
+			Priority: room.Priority,
+			DueAt:    room.DueAt,
+		})
+	}
+
+	for _, room := range breached {
+		s.publish(ctx, room.ID, Alert{
+			Type:       events.TypeSLABreached,
+			RoomID:     fmt.Sprint(room.ID),
+			Target:     t.target,
+			Priority:   room.Priority,
+			DueAt:      room.DueAt,
+			BreachedAt: &now,
+		})
+	}
+
+	return
+}
+
+func (s *Scheduler) publish(ctx context.Context, roomID uint, alert Alert) {
+	for _, topic := range []string{events.RoomTopic(roomID), events.QueueTopic} {
+		if err := s.Events.Publish(ctx, topic, alert.Type, alert); err != nil {
+			zap.L().Error("failed to publish sla alert", zap.String("type", alert.Type), zap.Error(err))
+		}
+	}
+}
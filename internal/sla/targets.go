@@ -0,0 +1,50 @@
+package sla
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Target is how quickly support has to reply to rooms of a priority
+type Target struct {
+	// From room creation to the first support message
+	FirstResponse time.Duration
+	// From a customer message to the next support message
+	NextResponse time.Duration
+}
+
+// Targets maps priorities to their targets
+type Targets map[string]Target
+
+var DefaultTargets = Targets{
+	PriorityLow:    {FirstResponse: 30 * time.Minute, NextResponse: time.Hour},
+	PriorityNormal: {FirstResponse: 10 * time.Minute, NextResponse: 20 * time.Minute},
+	PriorityHigh:   {FirstResponse: 5 * time.Minute, NextResponse: 10 * time.Minute},
+	PriorityUrgent: {FirstResponse: 2 * time.Minute, NextResponse: 5 * time.Minute},
+}
+
+// Warnings go out once this share of the target has elapsed
+const warnAfter = 0.8
+
+// ValidatePriority fails for unknown priorities
+func ValidatePriority(priority string) error {
+	switch priority {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return nil
+	}
+	return fmt.Errorf("unsupported priority: %s", priority)
+}
+
+// deadlines returns when to warn and when the target is breached, counting from start
+func deadlines(start time.Time, target time.Duration) (warnAt time.Time, dueAt time.Time) {
+	warnAt = start.Add(time.Duration(float64(target) * warnAfter))
+	dueAt = start.Add(target)
+	return
+}
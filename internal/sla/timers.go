@@ -0,0 +1,114 @@
+package sla
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+// Timers keeps the SLA deadlines of rooms up to date
+type Timers struct {
+	Targets Targets
+}
+
+func NewTimers(targets Targets) *Timers {
+	return &Timers{
+		Targets: targets,
+	}
+}
+
+// Start sets the first response deadline of a room about to be created
+func (t *Timers) Start(room *models.Room) {
+	if room.Priority == "" {
+		room.Priority = PriorityNormal
+	}
+
+	warnAt, dueAt := deadlines(room.CreatedAt, t.Targets[room.Priority].FirstResponse)
+	room.FirstResponseWarnAt = &warnAt
+	room.FirstResponseDueAt = &dueAt
+}
+
+// MessageSent updates the deadlines of the room after a message was stored. Support
+// messages stop pending timers, customer messages start the next response timer
+// once the first response was given.
+func (t *Timers) MessageSent(ctx context.Context, db bun.IDB, msg models.Message) (err error) {
+	switch msg.UserType {
+	case models.UserTypeSupport:
+		_, err = db.NewUpdate().
+			Model((*models.Room)(nil)).
+			Set("first_response_warn_at = NULL").
+			Set("first_response_due_at = NULL").
+			Set("next_response_warn_at = NULL").
+			Set("next_response_due_at = NULL").
+			Where("id = ?", msg.RoomID).
+			Exec(ctx)
+
+	case models.UserTypeCustomer:
+		var room models.Room
+		err = db.NewSelect().
+			Model(&room).
+			Column("priority").
+			Where("id = ?", msg.RoomID).
+			Scan(ctx)
+		if err != nil {
+			return
+		}
+
+		warnAt, dueAt := deadlines(msg.Timestamp, t.Targets[room.Priority].NextResponse)
+		_, err = db.NewUpdate().
+			Model((*models.Room)(nil)).
+			Set("next_response_warn_at = ?", warnAt).
+			Set("next_response_due_at = ?", dueAt).
+			Where("id = ?", msg.RoomID).
+			Where("archived_at IS NULL").
+			Where("first_response_due_at IS NULL").
+			Where("next_response_due_at IS NULL").
+			Exec(ctx)
+	}
+	return
+}
+
+// Reopen restarts the deadlines of a reopened room from given time. Rooms which
+// never got a first response wait for it again, others for the next response.
+func (t *Timers) Reopen(room *models.Room, at time.Time) {
+	target := t.Targets[room.Priority]
+
+	if room.FirstResponseDueAt != nil {
+		warnAt, dueAt := deadlines(at, target.FirstResponse)
+		room.FirstResponseWarnAt, room.FirstResponseDueAt = &warnAt, &dueAt
+		room.NextResponseWarnAt, room.NextResponseDueAt = nil, nil
+		return
+	}
+
+	warnAt, dueAt := deadlines(at, target.NextResponse)
+	room.NextResponseWarnAt, room.NextResponseDueAt = &warnAt, &dueAt
+}
+
+// SetPriority changes the priority of the room and moves pending deadlines to
+// match the targets of the new priority
+func (t *Timers) SetPriority(room *models.Room, priority string) {
+	oldTarget, newTarget := t.Targets[room.Priority], t.Targets[priority]
+	room.Priority = priority
+
+	if room.FirstResponseDueAt != nil {
+		room.FirstResponseWarnAt, room.FirstResponseDueAt = reschedule(room.CreatedAt, newTarget.FirstResponse, room.FirstResponseWarnAt)
+	}
+
+	if room.NextResponseDueAt != nil {
+		start := room.NextResponseDueAt.Add(-oldTarget.NextResponse)
+		room.NextResponseWarnAt, room.NextResponseDueAt = reschedule(start, newTarget.NextResponse, room.NextResponseWarnAt)
+	}
+}
+
+// reschedule computes new deadlines. Warnings which already went out stay cleared.
+func reschedule(start time.Time, target time.Duration, previousWarnAt *time.Time) (warnAt *time.Time, dueAt *time.Time) {
+	newWarnAt, newDueAt := deadlines(start, target)
+	if previousWarnAt != nil {
+		warnAt = &newWarnAt
+	}
+	dueAt = &newDueAt
+	return
+}
@@ -0,0 +1,77 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+func TestStartSetsFirstResponseDeadline(t *testing.T) {
+	timers := NewTimers(DefaultTargets)
+	room := models.Room{CreatedAt: time.Now()}
+
+	timers.Start(&room)
+
+	if room.Priority != PriorityNormal {
+		t.Fatalf("expected normal priority, got %s", room.Priority)
+	}
+	if due := room.FirstResponseDueAt.Sub(room.CreatedAt); due != DefaultTargets[PriorityNormal].FirstResponse {
+		t.Fatalf("unexpected first response target %s", due)
+	}
+	if !room.FirstResponseWarnAt.Before(*room.FirstResponseDueAt) {
+		t.Fatal("expected warning before the deadline")
+	}
+}
+
+func TestSetPriorityMovesPendingDeadlines(t *testing.T) {
+	timers := NewTimers(DefaultTargets)
+	createdAt := time.Now().Add(-time.Minute)
+	room := models.Room{CreatedAt: createdAt}
+	timers.Start(&room)
+
+	customerMessageAt := time.Now()
+	_, nextDue := deadlines(customerMessageAt, DefaultTargets[PriorityNormal].NextResponse)
+	room.NextResponseDueAt = &nextDue
+
+	timers.SetPriority(&room, PriorityUrgent)
+
+	urgent := DefaultTargets[PriorityUrgent]
+	if !room.FirstResponseDueAt.Equal(createdAt.Add(urgent.FirstResponse)) {
+		t.Fatalf("unexpected first response deadline %s", room.FirstResponseDueAt)
+	}
+	if !room.NextResponseDueAt.Equal(customerMessageAt.Add(urgent.NextResponse)) {
+		t.Fatalf("unexpected next response deadline %s", room.NextResponseDueAt)
+	}
+	if room.NextResponseWarnAt != nil {
+		t.Fatal("expected sent warning to stay cleared")
+	}
+}
+
+func TestReopenRestartsDeadlines(t *testing.T) {
+	timers := NewTimers(DefaultTargets)
+	room := models.Room{CreatedAt: time.Now().Add(-time.Hour), Priority: PriorityHigh}
+	reopenedAt := time.Now()
+
+	// Answered before it was archived
+	timers.Reopen(&room, reopenedAt)
+
+	high := DefaultTargets[PriorityHigh]
+	if room.FirstResponseDueAt != nil {
+		t.Fatal("expected answered room not to wait for a first response")
+	}
+	if room.NextResponseDueAt == nil || !room.NextResponseDueAt.Equal(reopenedAt.Add(high.NextResponse)) {
+		t.Fatalf("unexpected next response deadline %v", room.NextResponseDueAt)
+	}
+
+	// Never answered
+	timers.Start(&room)
+	timers.Reopen(&room, reopenedAt)
+
+	if !room.FirstResponseDueAt.Equal(reopenedAt.Add(high.FirstResponse)) {
+		t.Fatalf("unexpected first response deadline %s", room.FirstResponseDueAt)
+	}
+	if room.NextResponseDueAt != nil {
+		t.Fatal("expected next response timer to wait for the first response")
+	}
+}
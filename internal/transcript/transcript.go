@@ -0,0 +1,105 @@
+package transcript
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+
+	"github.com/helpify-project/backend/internal/database/models"
+)
+
+const (
+	RoleCustomer = "customer"
+	RoleAgent    = "agent"
+	RoleNote     = "note"
+	RoleSystem   = "system"
+)
+
+// Role names the kind of sender of a message
+func Role(userType uint) string {
+	switch userType {
+	case models.UserTypeSupport:
+		return RoleAgent
+	case models.UserTypeInternal:
+		return RoleNote
+	case models.UserTypeSystem:
+		return RoleSystem
+	}
+	return RoleCustomer
+}
+
+// LineFromModel converts a message, leaving out the content of deleted ones
+func LineFromModel(msg models.Message) Line {
+	line := Line{
+		ID:        msg.ID,
+		Role:      Role(msg.UserType),
+		Timestamp: msg.Timestamp,
+		Message:   msg.Message,
+		EditedAt:  msg.EditedAt,
+	}
+
+	if msg.DeletedAt != nil {
+		line.Message = ""
+		line.Deleted = true
+	}
+	return line
+}
+
+// Stream writes messages of the room to w in order, without loading them all at
+// once. Internal notes are only included if asked for.
+func Stream(ctx context.Context, db *bun.DB, roomID uint, includeInternal bool, w Writer) (err error) {
+	query := db.NewSelect().
+		Model((*models.Message)(nil)).
+		Where("room_id = ?", roomID).
+		Order("id ASC")
+	if !includeInternal {
+		query = query.Where("user_type != ?", models.UserTypeInternal)
+	}
+
+	rows, err := query.Rows(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var msg models.Message
+		if err = db.ScanRow(ctx, rows, &msg); err != nil {
+			return
+		}
+
+		if err = w.WriteLine(LineFromModel(msg)); err != nil {
+			return
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+	return w.Close()
+}
+
+// collector keeps lines in memory
+type collector struct {
+	lines []Line
+}
+
+func (c *collector) WriteLine(line Line) error {
+	c.lines = append(c.lines, line)
+	return nil
+}
+
+func (c *collector) Close() error {
+	return nil
+}
+
+// Collect loads all messages of the room as transcript lines
+func Collect(ctx context.Context, db *bun.DB, roomID uint, includeInternal bool) (lines []Line, err error) {
+	c := &collector{}
+	if err = Stream(ctx, db, roomID, includeInternal, c); err != nil {
+		return
+	}
+
+	lines = c.lines
+	return
+}
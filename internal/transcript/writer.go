@@ -0,0 +1,165 @@
+package transcript
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "txt"
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+)
+
+// Line is a single message of a transcript
+type Line struct {
+	ID        uint       `json:"id"`
+	Role      string     `json:"role"`
+	Timestamp time.Time  `json:"timestamp"`
+	Message   string     `json:"message"`
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	Deleted   bool       `json:"deleted"`
+}
+
+// Writer writes transcript lines in some format as they come in. Close finishes
+// the document without closing the underlying writer.
+type Writer interface {
+	WriteLine(line Line) error
+	Close() error
+}
+
+// NewWriter returns a writer for given format
+func NewWriter(format string, w io.Writer, title string) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatText:
+		return &textWriter{w: w}, nil
+	case FormatHTML:
+		return newHTMLWriter(w, title)
+	case FormatCSV:
+		return newCSVWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported transcript format: %s", format)
+}
+
+// ContentType returns the MIME type of given format
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func displayMessage(line Line) string {
+	if line.Deleted {
+		return "(message deleted)"
+	}
+	return line.Message
+}
+
+// jsonWriter streams a JSON array of lines
+type jsonWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (j *jsonWriter) WriteLine(line Line) (err error) {
+	separator := ","
+	if !j.started {
+		separator = "["
+		j.started = true
+	}
+
+	var encoded []byte
+	if encoded, err = json.Marshal(line); err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintf(j.w, "%s\n%s", separator, encoded)
+	return
+}
+
+func (j *jsonWriter) Close() (err error) {
+	if !j.started {
+		_, err = io.WriteString(j.w, "[]\n")
+		return
+	}
+	_, err = io.WriteString(j.w, "\n]\n")
+	return
+}
+
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) WriteLine(line Line) (err error) {
+	_, err = fmt.Fprintf(t.w, "[%s] %s: %s\n", line.Timestamp.UTC().Format(time.RFC3339), line.Role, displayMessage(line))
+	return
+}
+
+func (t *textWriter) Close() error {
+	return nil
+}
+
+type htmlWriter struct {
+	w io.Writer
+}
+
+func newHTMLWriter(w io.Writer, title string) (*htmlWriter, error) {
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n<ol>\n",
+		html.EscapeString(title), html.EscapeString(title))
+	return &htmlWriter{w: w}, err
+}
+
+func (h *htmlWriter) WriteLine(line Line) (err error) {
+	timestamp := line.Timestamp.UTC().Format(time.RFC3339)
+	_, err = fmt.Fprintf(h.w, "<li class=\"%s\"><time datetime=\"%s\">%s</time> <strong>%s</strong>: %s</li>\n",
+		html.EscapeString(line.Role), timestamp, timestamp, html.EscapeString(line.Role), html.EscapeString(displayMessage(line)))
+	return
+}
+
+func (h *htmlWriter) Close() (err error) {
+	_, err = io.WriteString(h.w, "</ol>\n</body>\n</html>\n")
+	return
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w)}
+	return c, c.w.Write([]string{"id", "timestamp", "role", "message", "edited_at", "deleted"})
+}
+
+func (c *csvWriter) WriteLine(line Line) error {
+	var editedAt string
+	if line.EditedAt != nil {
+		editedAt = line.EditedAt.UTC().Format(time.RFC3339)
+	}
+
+	return c.w.Write([]string{
+		fmt.Sprint(line.ID),
+		line.Timestamp.UTC().Format(time.RFC3339),
+		line.Role,
+		displayMessage(line),
+		editedAt,
+		fmt.Sprint(line.Deleted),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
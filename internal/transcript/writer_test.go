@@ -0,0 +1,73 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLines = []Line{
+	{ID: 1, Role: RoleCustomer, Timestamp: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), Message: "Hi, <b>help</b>"},
+	{ID: 2, Role: RoleAgent, Timestamp: time.Date(2026, 10, 17, 12, 1, 0, 0, time.UTC), Deleted: true},
+}
+
+func render(t *testing.T, format string, lines []Line) string {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, "Room 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		if err = w.WriteLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestJSONWriterProducesArray(t *testing.T) {
+	for _, lines := range [][]Line{nil, testLines} {
+		var decoded []Line
+		if err := json.Unmarshal([]byte(render(t, FormatJSON, lines)), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != len(lines) {
+			t.Fatalf("expected %d lines, got %d", len(lines), len(decoded))
+		}
+	}
+}
+
+func TestTextWriter(t *testing.T) {
+	expected := "[2026-10-17T12:00:00Z] customer: Hi, <b>help</b>\n" +
+		"[2026-10-17T12:01:00Z] agent: (message deleted)\n"
+	if out := render(t, FormatText, testLines); out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestHTMLWriterEscapesMessages(t *testing.T) {
+	out := render(t, FormatHTML, testLines)
+	if strings.Contains(out, "<b>help</b>") || !strings.Contains(out, "&lt;b&gt;help&lt;/b&gt;") {
+		t.Fatalf("message was not escaped:\n%s", out)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	out := render(t, FormatCSV, testLines[:1])
+	expected := "id,timestamp,role,message,edited_at,deleted\n" +
+		"1,2026-10-17T12:00:00Z,customer,\"Hi, <b>help</b>\",,false\n"
+	if out != expected {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter("pdf", &bytes.Buffer{}, ""); err == nil {
+		t.Fatal("expected error")
+	}
+}